@@ -0,0 +1,175 @@
+package slimgo
+
+import (
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AccessLogFormat is the line format of the AccessLog middleware.
+type AccessLogFormat int
+
+const (
+	// AccessLogCommon is the Apache Common Log Format.
+	AccessLogCommon AccessLogFormat = iota
+	// AccessLogCombined is the Apache Combined Log Format, Common with referer and user agent.
+	AccessLogCombined
+	// AccessLogJSON writes one JSON object per request with latency, request ID and route.
+	AccessLogJSON
+)
+
+const commonLogTime = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLogOptions configures the AccessLog middleware.
+type AccessLogOptions struct {
+	// Output receives the lines, os.Stdout by default, e.g. a file from OpenORCreateFile.
+	Output io.Writer
+	// Format is the line format, AccessLogCommon by default.
+	Format AccessLogFormat
+	// SampleRate logs only this fraction of the requests, e.g. 0.1 for 10%.
+	// 0 logs all of them. Server errors are always logged.
+	SampleRate float64
+	// SkipPaths lists request paths or route patterns which are not logged, e.g. "/health".
+	SkipPaths []string
+}
+
+// accessLogEntry is a line of AccessLogJSON.
+type accessLogEntry struct {
+	Time      string  `json:"time"`
+	RequestID string  `json:"request_id,omitempty"`
+	ClientIP  string  `json:"client_ip"`
+	Method    string  `json:"method"`
+	URI       string  `json:"uri"`
+	Route     string  `json:"route,omitempty"`
+	Proto     string  `json:"proto"`
+	Status    int     `json:"status"`
+	Bytes     int     `json:"bytes"`
+	LatencyMS float64 `json:"latency_ms"`
+	Referer   string  `json:"referer,omitempty"`
+	UserAgent string  `json:"user_agent,omitempty"`
+}
+
+// AccessLog returns a middleware which writes a line for every request after
+// it was handled. Use it first, so responses of other middleware are logged.
+func AccessLog(opts AccessLogOptions) Handler {
+	if opts.Output == nil {
+		opts.Output = os.Stdout
+	}
+	l := &accessLogger{opts: opts, skip: make(map[string]bool, len(opts.SkipPaths))}
+	for _, p := range opts.SkipPaths {
+		l.skip[p] = true
+	}
+
+	return func(c Context) {
+		start := time.Now()
+		defer func() {
+			// the server answers a panic with 500, log it as such
+			if err := recover(); err != nil {
+				l.log(c, start, http.StatusInternalServerError)
+				panic(err)
+			}
+		}()
+		c.Next()
+		l.log(c, start, 0)
+	}
+}
+
+type accessLogger struct {
+	opts AccessLogOptions
+	skip map[string]bool
+	mu   sync.Mutex
+}
+
+// log writes the line of the request handled by c. A status other than 0
+// replaces the one written to the response.
+func (l *accessLogger) log(c Context, start time.Time, status int) {
+	opts := &l.opts
+	req := c.Request()
+	if l.skip[req.URL.Path] || l.skip[c.RegRelativePath()] {
+		return
+	}
+	var written int
+	if sw, ok := c.ResponseWriter().(statusWriter); ok {
+		if status == 0 {
+			status = sw.Status()
+		}
+		written = sw.Size()
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if opts.SampleRate > 0 && opts.SampleRate < 1 && status < 500 && rand.Float64() >= opts.SampleRate {
+		return
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if opts.Format == AccessLogJSON {
+		requestID, _ := c.Data(RequestIDKey)
+		id, _ := requestID.(string)
+		b, err := json.Marshal(&accessLogEntry{
+			Time:      start.Format(time.RFC3339Nano),
+			RequestID: id,
+			ClientIP:  c.ClientIP(),
+			Method:    req.Method,
+			URI:       req.RequestURI,
+			Route:     c.RegRelativePath(),
+			Proto:     req.Proto,
+			Status:    status,
+			Bytes:     written,
+			LatencyMS: float64(time.Since(start)) / float64(time.Millisecond),
+			Referer:   req.Referer(),
+			UserAgent: req.UserAgent(),
+		})
+		if err != nil {
+			return
+		}
+		buf.Write(b)
+	} else {
+		user := "-"
+		if u, _, ok := req.BasicAuth(); ok && u != "" {
+			user = strconv.Quote(u)
+			user = user[1 : len(user)-1]
+		}
+		size := "-"
+		if written > 0 {
+			size = strconv.Itoa(written)
+		}
+
+		buf.WriteString(c.ClientIP())
+		buf.WriteString(" - ")
+		buf.WriteString(user)
+		buf.WriteString(" [")
+		buf.WriteString(start.Format(commonLogTime))
+		buf.WriteString("] ")
+		buf.WriteString(strconv.Quote(req.Method + " " + req.RequestURI + " " + req.Proto))
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.Itoa(status))
+		buf.WriteByte(' ')
+		buf.WriteString(size)
+		if opts.Format == AccessLogCombined {
+			buf.WriteByte(' ')
+			buf.WriteString(quoteOrDash(req.Referer()))
+			buf.WriteByte(' ')
+			buf.WriteString(quoteOrDash(req.UserAgent()))
+		}
+	}
+	buf.WriteByte('\n')
+
+	l.mu.Lock()
+	_, _ = opts.Output.Write(buf.Bytes())
+	l.mu.Unlock()
+}
+
+// quoteOrDash quotes a header for Combined lines, "-" if it is empty.
+func quoteOrDash(s string) string {
+	if s == "" {
+		return `"-"`
+	}
+	return strconv.Quote(s)
+}
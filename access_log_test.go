@@ -0,0 +1,44 @@
+package slimgo
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAccessLogStatus(t *testing.T) {
+	var out bytes.Buffer
+	s := New()
+	s.SetMode(Release)
+	s.SetMaxBodySize(4)
+	s.Use(AccessLog(AccessLogOptions{Output: &out, Format: AccessLogJSON}), RequestID(RequestIDOptions{}))
+	s.GET("/ok", func(c Context) { c.String(http.StatusOK, "ok") })
+	s.GET("/panic", func(c Context) { panic("boom") })
+	s.POST("/upload", func(c Context) { c.String(http.StatusOK, "ok") })
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want string
+	}{
+		{"ok", httptest.NewRequest(http.MethodGet, "/ok", nil), `"status":200`},
+		{"panic", httptest.NewRequest(http.MethodGet, "/panic", nil), `"status":500`},
+		{"body too large", httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("too large")), `"status":413`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out.Reset()
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, tt.req)
+			line := out.String()
+			if !strings.Contains(line, tt.want) || !strings.Contains(line, `"request_id":"`) {
+				t.Errorf("access log = %q, want %s with a request ID", line, tt.want)
+			}
+			if w.Header().Get(RequestIDHeader) == "" {
+				t.Errorf("response has no %s", RequestIDHeader)
+			}
+		})
+	}
+}
@@ -15,6 +15,9 @@ const (
 	MIMEMSGPACK           = "application/x-msgpack"
 	MIMEMSGPACK2          = "application/msgpack"
 	MIMEYAML              = "application/x-yaml"
+	MIMEJSONPatch         = "application/json-patch+json"
+	MIMEMergePatch        = "application/merge-patch+json"
+	MIMENDJSON            = "application/x-ndjson"
 )
 
 // Binding describes the interface which needs to be implemented for binding the
@@ -57,6 +60,17 @@ type StructValidator interface {
 
 var Validator StructValidator = &defaultValidator{}
 
+// These implement the Binding interface and can be used to bind the request
+// from a specific source regardless of the method and content type.
+var (
+	JSON       BindingBody = jsonBinding{}
+	JSONPatch  BindingBody = jsonPatchBinding{}
+	MergePatch BindingBody = mergePatchBinding{}
+	Query      Binding     = queryBinding{}
+	Header     Binding     = headerBinding{}
+	Uri        BindingUri  = uriBinding{}
+)
+
 // Default returns the appropriate Binding instance based on the HTTP method
 // and the content type.
 func Default(method, contentType string) Binding {
@@ -67,6 +81,10 @@ func Default(method, contentType string) Binding {
 	switch contentType {
 	case MIMEJSON:
 		return &jsonBinding{}
+	case MIMEJSONPatch:
+		return JSONPatch
+	case MIMEMergePatch:
+		return MergePatch
 	case MIMEMultipartPOSTForm:
 		return &formMultipartBinding{}
 	default: // case MIMEPOSTForm:
@@ -0,0 +1,91 @@
+package binding
+
+import (
+	"reflect"
+	"sync"
+)
+
+// fieldInfo is the binding plan of a struct field, parsed once per type and tag.
+type fieldInfo struct {
+	field reflect.StructField
+	index int
+	// key is the tag name or the field name, empty when the field is ignored.
+	key string
+	opt setOptions
+}
+
+// emptyFieldInfo is used for the bound value itself and for nested structs.
+var emptyFieldInfo = &fieldInfo{}
+
+type structKey struct {
+	typ reflect.Type
+	tag string
+}
+
+// structCache caches the []*fieldInfo of struct types by structKey.
+var structCache sync.Map
+
+// customTypeCache caches the result of isCustomType by reflect.Type.
+var customTypeCache sync.Map
+
+// cachedFields returns the binding plan of the exported fields of t for tag.
+func cachedFields(t reflect.Type, tag string) []*fieldInfo {
+	key := structKey{t, tag}
+	if fields, ok := structCache.Load(key); ok {
+		return fields.([]*fieldInfo)
+	}
+
+	fields := make([]*fieldInfo, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous { // unexported
+			continue
+		}
+		fields = append(fields, newFieldInfo(sf, i, tag))
+	}
+
+	actual, _ := structCache.LoadOrStore(key, fields)
+	return actual.([]*fieldInfo)
+}
+
+func newFieldInfo(field reflect.StructField, index int, tag string) *fieldInfo {
+	info := &fieldInfo{field: field, index: index}
+
+	tagValue, opts := head(field.Tag.Get(tag), ",")
+	if tagValue == "-" { // just ignoring this field
+		return info
+	}
+	if tagValue == "" { // default value is FieldName
+		tagValue = field.Name
+	}
+	info.key = tagValue
+
+	var opt string
+	for len(opts) > 0 {
+		opt, opts = head(opts, ",")
+
+		if k, v := head(opt, "="); k == "default" {
+			info.opt.isDefaultExists = true
+			info.opt.defaultValue = v
+		}
+	}
+	return info
+}
+
+// isCustomType reports whether values of t are set from a single string
+// by a converter or encoding.TextUnmarshaler.
+func isCustomType(t reflect.Type) bool {
+	if custom, ok := customTypeCache.Load(t); ok {
+		return custom.(bool)
+	}
+	custom := resolveCustomType(t)
+	customTypeCache.Store(t, custom)
+	return custom
+}
+
+func resetCustomTypeCache() {
+	customTypeCache.Range(func(key, _ interface{}) bool {
+		customTypeCache.Delete(key)
+		return true
+	})
+}
@@ -0,0 +1,73 @@
+package binding
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ConverterFunc converts a form, query, header or uri value into a value of
+// the type it was registered for.
+type ConverterFunc func(value string) (interface{}, error)
+
+var converters sync.Map // map[reflect.Type]ConverterFunc
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// RegisterConverter registers fn for the type of sample, e.g. a UUID, decimal
+// or enum type. Fields of that type and pointers to it are set by fn.
+// Converters take precedence over encoding.TextUnmarshaler.
+func RegisterConverter(sample interface{}, fn ConverterFunc) {
+	t := reflect.TypeOf(sample)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	converters.Store(t, fn)
+	resetCustomTypeCache()
+}
+
+func lookupConverter(t reflect.Type) (ConverterFunc, bool) {
+	if fn, ok := converters.Load(t); ok {
+		return fn.(ConverterFunc), true
+	}
+	return nil, false
+}
+
+func resolveCustomType(t reflect.Type) bool {
+	if _, ok := lookupConverter(t); ok {
+		return true
+	}
+	if t == timeType {
+		return false
+	}
+	return reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// trySetCustom sets value by a registered converter or encoding.TextUnmarshaler.
+func trySetCustom(val string, value reflect.Value) (bool, error) {
+	if fn, ok := lookupConverter(value.Type()); ok {
+		v, err := fn(val)
+		if err != nil {
+			return true, err
+		}
+		rv := reflect.ValueOf(v)
+		if !rv.IsValid() {
+			value.Set(reflect.Zero(value.Type()))
+			return true, nil
+		}
+		if rv.Kind() == reflect.Ptr && rv.Type().Elem() == value.Type() {
+			rv = rv.Elem()
+		}
+		if !rv.Type().AssignableTo(value.Type()) {
+			return true, fmt.Errorf("converter returned %s for %s", rv.Type(), value.Type())
+		}
+		value.Set(rv)
+		return true, nil
+	}
+
+	if !isCustomType(value.Type()) || !value.CanAddr() {
+		return false, nil
+	}
+	return true, value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+}
@@ -0,0 +1,113 @@
+package binding
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testLevel int
+
+func (l *testLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.New("unknown level")
+	}
+	return nil
+}
+
+type testCode string
+
+type testCents int64
+
+// testBoth implements encoding.TextUnmarshaler and has a converter.
+type testBoth string
+
+func (b *testBoth) UnmarshalText(text []byte) error {
+	*b = "text:" + testBoth(text)
+	return nil
+}
+
+type testWrong int
+
+func init() {
+	RegisterConverter(testCode(""), func(value string) (interface{}, error) {
+		if value == "bad" {
+			return nil, errors.New("bad code")
+		}
+		return testCode(strings.ToUpper(value)), nil
+	})
+	RegisterConverter((*testCents)(nil), func(value string) (interface{}, error) {
+		c := testCents(len(value))
+		return &c, nil
+	})
+	RegisterConverter(testBoth(""), func(value string) (interface{}, error) {
+		return testBoth("conv:" + value), nil
+	})
+	RegisterConverter(testWrong(0), func(value string) (interface{}, error) {
+		return value, nil
+	})
+}
+
+type converterForm struct {
+	Level  testLevel   `form:"level"`
+	LevelP *testLevel  `form:"level_p"`
+	Levels []testLevel `form:"levels"`
+	Code   testCode    `form:"code"`
+	CodeP  *testCode   `form:"code_p"`
+	Codes  []testCode  `form:"codes"`
+	Cents  testCents   `form:"cents"`
+	Both   testBoth    `form:"both"`
+	Wrong  testWrong   `form:"wrong"`
+}
+
+func TestMapFormConverters(t *testing.T) {
+	high := testLevel(2)
+	abc := testCode("ABC")
+
+	tests := []struct {
+		name     string
+		values   url.Values
+		want     converterForm
+		errField string
+	}{
+		{"text unmarshaler", url.Values{"level": {"high"}}, converterForm{Level: 2}, ""},
+		{"text unmarshaler pointer", url.Values{"level_p": {"high"}}, converterForm{LevelP: &high}, ""},
+		{"text unmarshaler slice", url.Values{"levels": {"low", "high"}}, converterForm{Levels: []testLevel{1, 2}}, ""},
+		{"text unmarshaler error", url.Values{"level": {"medium"}}, converterForm{}, "level"},
+		{"text unmarshaler slice error", url.Values{"levels": {"low", "medium"}}, converterForm{}, "levels"},
+		{"converter", url.Values{"code": {"abc"}}, converterForm{Code: "ABC"}, ""},
+		{"converter pointer", url.Values{"code_p": {"abc"}}, converterForm{CodeP: &abc}, ""},
+		{"converter slice", url.Values{"codes": {"a", "b"}}, converterForm{Codes: []testCode{"A", "B"}}, ""},
+		{"converter returning pointer", url.Values{"cents": {"1234"}}, converterForm{Cents: 4}, ""},
+		{"converter before text unmarshaler", url.Values{"both": {"x"}}, converterForm{Both: "conv:x"}, ""},
+		{"converter error", url.Values{"code": {"bad"}}, converterForm{}, "code"},
+		{"converter pointer error", url.Values{"code_p": {"bad"}}, converterForm{}, "code_p"},
+		{"converter wrong type", url.Values{"wrong": {"1"}}, converterForm{}, "wrong"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got converterForm
+			err := mapForm(&got, tt.values)
+			if tt.errField != "" {
+				var fe *FieldError
+				if !errors.As(err, &fe) || fe.Field != tt.errField || fe.Rule != "type" {
+					t.Fatalf("got error %#v, want type error of %q", err, tt.errField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
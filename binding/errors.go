@@ -0,0 +1,122 @@
+package binding
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+)
+
+// FieldError describes a binding or validation failure of a single field.
+type FieldError struct {
+	// Field is the field path, e.g. "Name" or "Address.City".
+	Field string
+	// Rule is the failing validator rule ("required", "email", ...) or
+	// "type" when the request value can not be converted.
+	Rule string
+	// Param holds the rule parameters, e.g. "1|10" for "length(1|10)",
+	// or the target type name when Rule is "type".
+	Param string
+	// Value is the offending value when it is known.
+	Value string
+	// Message is the localized message set by Translate.
+	Message string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Errors is a list of field errors returned by bindings and the default validator.
+type Errors []*FieldError
+
+func (es Errors) Error() string {
+	msgs := make([]string, 0, len(es))
+	for _, e := range es {
+		msgs = append(msgs, e.Error())
+	}
+	return strings.Join(msgs, ";")
+}
+
+// reValidatorMessage extracts value and params from govalidator messages like
+// "abc does not validate as length(5|10)".
+var reValidatorMessage = regexp.MustCompile(`^(.*) does not validate as \w+(?:\((.*)\))?$`)
+
+// fromValidatorError converts govalidator errors into Errors.
+func fromValidatorError(err error) error {
+	var result Errors
+	collectValidatorErrors(err, &result)
+	if len(result) == 0 {
+		return err
+	}
+	return result
+}
+
+func collectValidatorErrors(err error, result *Errors) {
+	switch e := err.(type) {
+	case govalidator.Errors:
+		for _, sub := range e {
+			collectValidatorErrors(sub, result)
+		}
+	case govalidator.Error:
+		fe := &FieldError{
+			Field: strings.Join(append(e.Path, e.Name), "."),
+			Rule:  e.Validator,
+			Err:   e,
+		}
+		if m := reValidatorMessage.FindStringSubmatch(e.Err.Error()); m != nil {
+			fe.Value, fe.Param = m[1], m[2]
+		}
+		*result = append(*result, fe)
+	default:
+		if err != nil {
+			*result = append(*result, &FieldError{Rule: "default", Err: err})
+		}
+	}
+}
+
+// newTypeError wraps a conversion error of the request value for key.
+func newTypeError(key, val string, value reflect.Value, err error) *FieldError {
+	return &FieldError{
+		Field: key,
+		Rule:  "type",
+		Param: typeName(value.Type()),
+		Value: val,
+		Err:   fmt.Errorf("%s: %w", key, err),
+	}
+}
+
+func typeName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if t.PkgPath() == "time" && t.Name() == "Duration" {
+			return "duration"
+		}
+		return "int"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "uint"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Struct:
+		if t.PkgPath() == "time" && t.Name() == "Time" {
+			return "time"
+		}
+	}
+	return t.String()
+}
@@ -56,24 +56,63 @@ func (formMultipartBinding) Bind(req *http.Request, obj interface{}) error {
 	if err := req.ParseMultipartForm(defaultMemory); err != nil {
 		return err
 	}
-	if err := mappingByPtr(obj, (*multipartRequest)(req), "form"); err != nil {
+	if err := mappingByPtr(obj, newMultipartSource(req.MultipartForm), "form"); err != nil {
 		return err
 	}
 
 	return validate(obj)
 }
 
-type multipartRequest http.Request
+// multipartSource binds values and files of a multipart form.
+type multipartSource struct {
+	form  formSource
+	files map[string][]*multipart.FileHeader
+}
+
+var _ nestedSetter = (*multipartSource)(nil)
 
-var _ setter = (*multipartRequest)(nil)
+func newMultipartSource(form *multipart.Form) *multipartSource {
+	return &multipartSource{form: newFormSource(form.Value), files: normalizeFileKeys(form.File)}
+}
 
 // TrySet tries to set a value by the multipart request with the binding a form file
-func (r *multipartRequest) TrySet(value reflect.Value, field reflect.StructField, key string, opt setOptions) (isSetted bool, err error) {
-	if files := r.MultipartForm.File[key]; len(files) != 0 {
+func (r *multipartSource) TrySet(value reflect.Value, field reflect.StructField, key string, opt setOptions) (isSetted bool, err error) {
+	if files := r.files[key]; len(files) != 0 {
 		return setByMultipartFormFile(value, field, files)
 	}
 
-	return setByForm(value, field, r.MultipartForm.Value, key, opt)
+	return setByForm(value, field, r.form, key, opt)
+}
+
+func (r *multipartSource) sub(prefix string) (nestedSetter, bool) {
+	values, hasValues := r.form.sub(prefix)
+	var files map[string][]*multipart.FileHeader
+	for key, fhs := range r.files {
+		if rest, ok := trimKeyPrefix(key, prefix); ok {
+			if files == nil {
+				files = make(map[string][]*multipart.FileHeader)
+			}
+			files[rest] = fhs
+		}
+	}
+	if !hasValues && files == nil {
+		return nil, false
+	}
+	if !hasValues {
+		values = formSource{}
+	}
+	return &multipartSource{form: values.(formSource), files: files}, true
+}
+
+func (r *multipartSource) indexes(prefix string) ([]int, error) {
+	var idx keyIndexes
+	for key := range r.form {
+		idx.add(key, prefix)
+	}
+	for key := range r.files {
+		idx.add(key, prefix)
+	}
+	return idx.sorted(prefix)
 }
 
 func setByMultipartFormFile(value reflect.Value, field reflect.StructField, files []*multipart.FileHeader) (isSetted bool, err error) {
@@ -12,14 +12,17 @@ import (
 
 var errUnknownType = errors.New("Unknown type")
 
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
 func mapForm(ptr interface{}, form map[string][]string) error {
 	return mapFormByTag(ptr, form, "form")
 }
 
-var emptyField = reflect.StructField{}
-
 func mapFormByTag(ptr interface{}, form map[string][]string, tag string) error {
-	return mappingByPtr(ptr, formSource(form), tag)
+	return mappingByPtr(ptr, newFormSource(form), tag)
 }
 
 // setter tries to set value on a walking by fields of a struct
@@ -37,11 +40,11 @@ func (form formSource) TrySet(value reflect.Value, field reflect.StructField, ta
 }
 
 func mappingByPtr(ptr interface{}, setter setter, tag string) error {
-	_, err := mapping(reflect.ValueOf(ptr), emptyField, setter, tag)
+	_, err := mapping(reflect.ValueOf(ptr), emptyFieldInfo, setter, tag)
 	return err
 }
 
-func mapping(value reflect.Value, field reflect.StructField, setter setter, tag string) (bool, error) {
+func mapping(value reflect.Value, info *fieldInfo, setter setter, tag string) (bool, error) {
 	var vKind = value.Kind()
 
 	if vKind == reflect.Ptr {
@@ -51,7 +54,7 @@ func mapping(value reflect.Value, field reflect.StructField, setter setter, tag
 			isNew = true
 			vPtr = reflect.New(value.Type().Elem())
 		}
-		isSetted, err := mapping(vPtr.Elem(), field, setter, tag)
+		isSetted, err := mapping(vPtr.Elem(), info, setter, tag)
 		if err != nil {
 			return false, err
 		}
@@ -61,8 +64,8 @@ func mapping(value reflect.Value, field reflect.StructField, setter setter, tag
 		return isSetted, nil
 	}
 
-	if vKind != reflect.Struct || !field.Anonymous {
-		ok, err := tryToSetValue(value, field, setter, tag)
+	if vKind != reflect.Struct || !info.field.Anonymous {
+		ok, err := tryToSetValue(value, info, setter, tag)
 		if err != nil {
 			return false, err
 		}
@@ -71,16 +74,10 @@ func mapping(value reflect.Value, field reflect.StructField, setter setter, tag
 		}
 	}
 
-	if vKind == reflect.Struct {
-		tValue := value.Type()
-
+	if vKind == reflect.Struct && !isCustomType(value.Type()) {
 		var isSetted bool
-		for i := 0; i < value.NumField(); i++ {
-			sf := tValue.Field(i)
-			if sf.PkgPath != "" && !sf.Anonymous { // unexported
-				continue
-			}
-			ok, err := mapping(value.Field(i), tValue.Field(i), setter, tag)
+		for _, fi := range cachedFields(value.Type(), tag) {
+			ok, err := mapping(value.Field(fi.index), fi, setter, tag)
 			if err != nil {
 				return false, err
 			}
@@ -96,34 +93,25 @@ type setOptions struct {
 	defaultValue    string
 }
 
-func tryToSetValue(value reflect.Value, field reflect.StructField, setter setter, tag string) (bool, error) {
-	var tagValue string
-	var setOpt setOptions
-
-	tagValue = field.Tag.Get(tag)
-	tagValue, opts := head(tagValue, ",")
-
-	if tagValue == "-" { // just ignoring this field
+func tryToSetValue(value reflect.Value, info *fieldInfo, setter setter, tag string) (bool, error) {
+	if info.key == "" { // ignored field or "emptyFieldInfo"
 		return false, nil
 	}
-	if tagValue == "" { // default value is FieldName
-		tagValue = field.Name
+
+	field, tagValue := info.field, info.key
+	ok, err := false, error(nil)
+	if ns, isNested := setter.(nestedSetter); isNested {
+		ok, err = trySetNested(value, field, ns, tagValue, tag)
 	}
-	if tagValue == "" { // when field is "emptyField" variable
-		return false, nil
+	if !ok && err == nil {
+		ok, err = setter.TrySet(value, field, tagValue, info.opt)
 	}
-
-	var opt string
-	for len(opts) > 0 {
-		opt, opts = head(opts, ",")
-
-		if k, v := head(opt, "="); k == "default" {
-			setOpt.isDefaultExists = true
-			setOpt.defaultValue = v
+	if err != nil {
+		if _, isField := err.(*FieldError); !isField {
+			err = newTypeError(tagValue, "", value, err)
 		}
 	}
-
-	return setter.TrySet(value, field, tagValue, setOpt)
+	return ok, err
 }
 
 func setByForm(value reflect.Value, field reflect.StructField, form map[string][]string, tagValue string, opt setOptions) (isSetted bool, err error) {
@@ -131,36 +119,52 @@ func setByForm(value reflect.Value, field reflect.StructField, form map[string][
 	if !ok && !opt.isDefaultExists {
 		return false, nil
 	}
+	if !ok {
+		vs = []string{opt.defaultValue}
+	}
 
-	switch value.Kind() {
+	kind := value.Kind()
+	if isCustomType(value.Type()) {
+		kind = reflect.String
+	}
+
+	switch kind {
 	case reflect.Slice:
-		if !ok {
-			vs = []string{opt.defaultValue}
-		}
-		return true, setSlice(vs, value, field)
+		err = setSlice(vs, value, field)
 	case reflect.Array:
-		if !ok {
-			vs = []string{opt.defaultValue}
-		}
 		if len(vs) != value.Len() {
-			return false, fmt.Errorf("%q is not valid value for %s", vs, value.Type().String())
+			return false, newTypeError(tagValue, strings.Join(vs, ","), value,
+				fmt.Errorf("%q is not valid value for %s", vs, value.Type().String()))
 		}
-		return true, setArray(vs, value, field)
+		err = setArray(vs, value, field)
 	default:
 		var val string
-		if !ok {
-			val = opt.defaultValue
-		}
-
 		if len(vs) > 0 {
 			val = vs[0]
 		}
-		return true, setWithProperType(val, value, field)
+		if err = setWithProperType(val, value, field); err != nil {
+			return false, newTypeError(tagValue, val, value, err)
+		}
+		return true, nil
 	}
+	if err != nil {
+		return false, newTypeError(tagValue, strings.Join(vs, ","), value, err)
+	}
+	return true, nil
 }
 
 func setWithProperType(val string, value reflect.Value, field reflect.StructField) error {
+	if ok, err := trySetCustom(val, value); ok {
+		return err
+	}
+
 	switch value.Kind() {
+	case reflect.Ptr:
+		ptr := reflect.New(value.Type().Elem())
+		if err := setWithProperType(val, ptr.Elem(), field); err != nil {
+			return err
+		}
+		value.Set(ptr)
 	case reflect.Int:
 		return setIntField(val, 0, value)
 	case reflect.Int8:
@@ -170,8 +174,7 @@ func setWithProperType(val string, value reflect.Value, field reflect.StructFiel
 	case reflect.Int32:
 		return setIntField(val, 32, value)
 	case reflect.Int64:
-		switch value.Interface().(type) {
-		case time.Duration:
+		if value.Type() == durationType {
 			return setTimeDuration(val, value, field)
 		}
 		return setIntField(val, 64, value)
@@ -194,8 +197,7 @@ func setWithProperType(val string, value reflect.Value, field reflect.StructFiel
 	case reflect.String:
 		value.SetString(val)
 	case reflect.Struct:
-		switch value.Interface().(type) {
-		case time.Time:
+		if value.Type() == timeType {
 			return setTimeField(val, field, value)
 		}
 		return json.Unmarshal([]byte(val), value.Addr().Interface())
@@ -0,0 +1,216 @@
+package binding
+
+import (
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Limits of nested form keys. A key breaking them fails the binding only when
+// it belongs to a field of the bound struct, others are ignored like any
+// unknown key.
+var (
+	// MaxFormDepth limits the number of segments in nested form keys
+	// such as "items[0][sku]" or "address.city".
+	MaxFormDepth = 8
+	// MaxFormIndex limits slice indexes in form keys such as "items[10]",
+	// so a request can not make the binding allocate huge slices.
+	MaxFormIndex = 1000
+	// MaxFormElements limits the number of distinct indexes bound into one slice.
+	MaxFormElements = 256
+)
+
+// nestedSetter is a setter which can bind nested structs and indexed slices.
+type nestedSetter interface {
+	setter
+	// sub returns the setter of keys below prefix, e.g. "city" for "address.city".
+	sub(prefix string) (nestedSetter, bool)
+	// indexes returns the sorted slice indexes used below prefix.
+	indexes(prefix string) ([]int, error)
+}
+
+// depthSetter is the setter of a nested struct, depth segments below the form.
+type depthSetter struct {
+	nestedSetter
+	depth int
+}
+
+func setterDepth(s nestedSetter) int {
+	if d, ok := s.(depthSetter); ok {
+		return d.depth
+	}
+	return 0
+}
+
+// normalizeFormKey turns bracket notation into dot notation:
+// "items[0][sku]" becomes "items.0.sku" and "tags[]" becomes "tags".
+func normalizeFormKey(key string) string {
+	if strings.IndexByte(key, '[') < 0 {
+		return key
+	}
+	normalized := strings.NewReplacer("][", ".", "[", ".", "]", "").Replace(key)
+	return strings.TrimSuffix(normalized, ".")
+}
+
+// newFormSource returns a formSource which also contains the normalized
+// form of bracketed keys. The original keys are kept as they are.
+func newFormSource(form map[string][]string) formSource {
+	var normalized formSource
+	for key, vs := range form {
+		nk := normalizeFormKey(key)
+		if nk == key {
+			continue
+		}
+		if normalized == nil {
+			normalized = make(formSource, len(form))
+			for k, v := range form {
+				normalized[k] = v
+			}
+		}
+		normalized[nk] = append(normalized[nk], vs...)
+	}
+	if normalized == nil {
+		return formSource(form)
+	}
+	return normalized
+}
+
+var _ nestedSetter = formSource(nil)
+
+func (form formSource) sub(prefix string) (nestedSetter, bool) {
+	var result formSource
+	for key, vs := range form {
+		if rest, ok := trimKeyPrefix(key, prefix); ok {
+			if result == nil {
+				result = make(formSource)
+			}
+			result[rest] = vs
+		}
+	}
+	return result, result != nil
+}
+
+func (form formSource) indexes(prefix string) ([]int, error) {
+	var idx keyIndexes
+	for key := range form {
+		idx.add(key, prefix)
+	}
+	return idx.sorted(prefix)
+}
+
+// trimKeyPrefix returns the rest of key below prefix, e.g. "city" for
+// "address.city" and "address".
+func trimKeyPrefix(key, prefix string) (string, bool) {
+	if len(key) <= len(prefix)+1 || key[len(prefix)] != '.' || key[:len(prefix)] != prefix {
+		return "", false
+	}
+	return key[len(prefix)+1:], true
+}
+
+// keyIndexes collects the distinct slice indexes of keys below a prefix.
+type keyIndexes struct {
+	seen       map[int]bool
+	result     []int
+	outOfRange bool
+}
+
+func (k *keyIndexes) add(key, prefix string) {
+	rest, ok := trimKeyPrefix(key, prefix)
+	if !ok || rest[0] < '0' || rest[0] > '9' {
+		return
+	}
+	seg, _ := head(rest, ".")
+	i, err := strconv.Atoi(seg)
+	if err != nil || k.seen[i] {
+		return
+	}
+	if i > MaxFormIndex {
+		k.outOfRange = true
+		return
+	}
+	if k.seen == nil {
+		k.seen = make(map[int]bool)
+	}
+	k.seen[i] = true
+	k.result = append(k.result, i)
+}
+
+func (k *keyIndexes) sorted(prefix string) ([]int, error) {
+	if k.outOfRange {
+		return nil, fmt.Errorf("form key %q has index out of range [0, %d]", prefix, MaxFormIndex)
+	}
+	if len(k.result) > MaxFormElements {
+		return nil, fmt.Errorf("form key %q has more than %d elements", prefix, MaxFormElements)
+	}
+	sort.Ints(k.result)
+	return k.result, nil
+}
+
+func normalizeFileKeys(files map[string][]*multipart.FileHeader) map[string][]*multipart.FileHeader {
+	result := make(map[string][]*multipart.FileHeader, len(files))
+	for key, fhs := range files {
+		result[key] = append(result[key], fhs...)
+		if nk := normalizeFormKey(key); nk != key {
+			result[nk] = append(result[nk], fhs...)
+		}
+	}
+	return result
+}
+
+// trySetNested binds value from the keys below key, e.g. "address.city" into
+// a struct field or "items.0.sku" into a slice of structs.
+func trySetNested(value reflect.Value, field reflect.StructField, setter nestedSetter, key string, tag string) (bool, error) {
+	if isCustomType(value.Type()) {
+		return false, nil
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		if value.Type() == timeType {
+			return false, nil
+		}
+		sub, ok := setter.sub(key)
+		if !ok {
+			return false, nil
+		}
+		depth := setterDepth(setter) + strings.Count(key, ".") + 1
+		if depth >= MaxFormDepth {
+			return false, fmt.Errorf("form key %q is nested deeper than %d", key, MaxFormDepth)
+		}
+		return mapping(value, emptyFieldInfo, depthSetter{sub, depth}, tag)
+	case reflect.Slice:
+		idx, err := setter.indexes(key)
+		if err != nil || len(idx) == 0 {
+			return false, err
+		}
+		slice := reflect.MakeSlice(value.Type(), idx[len(idx)-1]+1, idx[len(idx)-1]+1)
+		for _, i := range idx {
+			elemKey := key + "." + strconv.Itoa(i)
+			if _, err := setIndexed(slice.Index(i), field, setter, elemKey, tag); err != nil {
+				return false, err
+			}
+		}
+		value.Set(slice)
+		return true, nil
+	}
+	return false, nil
+}
+
+func setIndexed(elem reflect.Value, field reflect.StructField, setter nestedSetter, key string, tag string) (bool, error) {
+	if elem.Kind() == reflect.Ptr {
+		ptr := reflect.New(elem.Type().Elem())
+		ok, err := setIndexed(ptr.Elem(), field, setter, key, tag)
+		if ok && err == nil {
+			elem.Set(ptr)
+		}
+		return ok, err
+	}
+
+	if ok, err := trySetNested(elem, field, setter, key, tag); ok || err != nil {
+		return ok, err
+	}
+	return setter.TrySet(elem, field, key, setOptions{})
+}
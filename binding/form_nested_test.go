@@ -0,0 +1,87 @@
+package binding
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type nestedItem struct {
+	SKU string `form:"sku"`
+	Qty int    `form:"qty"`
+}
+
+type nestedNode struct {
+	Name     string       `form:"name"`
+	Children []nestedNode `form:"children"`
+}
+
+type nestedForm struct {
+	Name  string       `form:"name"`
+	Items []nestedItem `form:"items"`
+	Tags  []string     `form:"tags"`
+	Tree  nestedNode   `form:"tree"`
+}
+
+func TestMapFormNestedLimits(t *testing.T) {
+	deep := "tree" + strings.Repeat("[children][0]", 4) + "[name]"
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+	}{
+		{"nested keys", "name=a&items[0][sku]=x&items[1][qty]=2&tags[]=t", ""},
+		{"unbound index out of range", "name=a&ref[5000]=x", ""},
+		{"unbound key too deep", "name=a&x[a][b][c][d][e][f][g][h][i]=1", ""},
+		{"unbound too many elements", manyIndexes("ref", MaxFormElements+1), ""},
+		{"bound index out of range", "items[5000][sku]=x", "index out of range"},
+		{"bound too many elements", manyIndexes("items", MaxFormElements+1), "more than"},
+		{"bound key too deep", deep + "=x", "nested deeper"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var f nestedForm
+			err = mapForm(&f, values)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("mapForm: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("mapForm error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMapFormNestedValues(t *testing.T) {
+	values, _ := url.ParseQuery("items[0][sku]=a&items[0][qty]=2&items[2][sku]=c&tree[children][0][name]=leaf")
+	var f nestedForm
+	if err := mapForm(&f, values); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Items) != 3 || f.Items[0] != (nestedItem{"a", 2}) || f.Items[2].SKU != "c" {
+		t.Errorf("Items = %+v", f.Items)
+	}
+	if len(f.Tree.Children) != 1 || f.Tree.Children[0].Name != "leaf" {
+		t.Errorf("Tree = %+v", f.Tree)
+	}
+}
+
+func manyIndexes(key string, n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			sb.WriteByte('&')
+		}
+		sb.WriteString(key + "[" + strconv.Itoa(i) + "][sku]=x")
+	}
+	return sb.String()
+}
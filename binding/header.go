@@ -0,0 +1,29 @@
+package binding
+
+import (
+	"net/http"
+	"net/textproto"
+	"reflect"
+)
+
+type headerBinding struct{}
+
+func (headerBinding) Name() string {
+	return "header"
+}
+
+func (headerBinding) Bind(req *http.Request, obj interface{}) error {
+	if err := mappingByPtr(obj, headerSource(req.Header), "header"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+type headerSource map[string][]string
+
+var _ setter = headerSource(nil)
+
+// TrySet tries to set a value by the canonical form of the header key
+func (hs headerSource) TrySet(value reflect.Value, field reflect.StructField, tagValue string, opt setOptions) (isSetted bool, err error) {
+	return setByForm(value, field, hs, textproto.CanonicalMIMEHeaderKey(tagValue), opt)
+}
@@ -0,0 +1,195 @@
+package binding
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Catalog maps message keys to message templates of one language.
+//
+// Templates are looked up by "Field.rule", "rule.param", "rule" and finally
+// "default". The display name of a field can be set with "field:Field".
+// Templates may use the placeholders {field}, {value} and {param}; rule
+// parameters like "1|10" are also available as {min} and {max}.
+type Catalog map[string]string
+
+// DefaultLanguage is used when none of the requested languages has a catalog.
+var DefaultLanguage = "en"
+
+var catalogs = struct {
+	sync.RWMutex
+	m map[string]Catalog
+}{m: map[string]Catalog{
+	"en": catalogEN,
+	"zh": catalogZH,
+}}
+
+// RegisterCatalog adds messages for lang, overriding existing keys.
+func RegisterCatalog(lang string, catalog Catalog) {
+	lang = normalizeLang(lang)
+
+	catalogs.Lock()
+	defer catalogs.Unlock()
+
+	merged := make(Catalog, len(catalogs.m[lang])+len(catalog))
+	for k, v := range catalogs.m[lang] {
+		merged[k] = v
+	}
+	for k, v := range catalog {
+		merged[k] = v
+	}
+	catalogs.m[lang] = merged
+}
+
+// LoadCatalog reads a JSON object of key/template pairs and registers it for lang.
+func LoadCatalog(lang string, r io.Reader) error {
+	var catalog Catalog
+	if err := json.NewDecoder(r).Decode(&catalog); err != nil {
+		return err
+	}
+	RegisterCatalog(lang, catalog)
+	return nil
+}
+
+// Languages returns the languages which have a catalog.
+func Languages() []string {
+	catalogs.RLock()
+	defer catalogs.RUnlock()
+
+	langs := make([]string, 0, len(catalogs.m))
+	for lang := range catalogs.m {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// MatchLanguage returns the best registered language for an Accept-Language
+// header value, or DefaultLanguage.
+func MatchLanguage(acceptLanguage string) string {
+	type weighted struct {
+		lang string
+		q    float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		lang, params := head(strings.TrimSpace(part), ";")
+		if lang == "" || lang == "*" {
+			continue
+		}
+		q := 1.0
+		if k, v := head(strings.TrimSpace(params), "="); k == "q" {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			tags = append(tags, weighted{normalizeLang(lang), q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	catalogs.RLock()
+	defer catalogs.RUnlock()
+
+	for _, t := range tags {
+		if _, ok := catalogs.m[t.lang]; ok {
+			return t.lang
+		}
+		if base, _ := head(t.lang, "-"); base != t.lang {
+			if _, ok := catalogs.m[base]; ok {
+				return base
+			}
+		}
+	}
+	return DefaultLanguage
+}
+
+// Translate returns a copy of err with localized messages for lang.
+// Errors other than *FieldError and Errors are returned unchanged.
+func Translate(err error, lang string) error {
+	var fe *FieldError
+	switch e := err.(type) {
+	case Errors:
+		result := make(Errors, len(e))
+		for i := range e {
+			result[i] = translateField(e[i], lang)
+		}
+		return result
+	case *FieldError:
+		return translateField(e, lang)
+	}
+	if errors.As(err, &fe) {
+		return translateField(fe, lang)
+	}
+	return err
+}
+
+func translateField(e *FieldError, lang string) *FieldError {
+	catalog := findCatalog(lang)
+	tpl, ok := lookupMessage(catalog, e)
+	if !ok {
+		// registered catalogs need not have a "default" message
+		tpl, ok = lookupMessage(findCatalog(DefaultLanguage), e)
+	}
+	if !ok {
+		return e
+	}
+
+	field := e.Field
+	if name, ok := catalog["field:"+e.Field]; ok {
+		field = name
+	}
+
+	params := strings.Split(e.Param, "|")
+	translated := *e
+	translated.Message = strings.NewReplacer(
+		"{field}", field,
+		"{value}", e.Value,
+		"{param}", strings.Join(params, ", "),
+		"{min}", params[0],
+		"{max}", params[len(params)-1],
+	).Replace(tpl)
+	return &translated
+}
+
+// findCatalog returns the catalog of lang, of its base language, e.g. "zh"
+// for "zh-TW", or of DefaultLanguage.
+func findCatalog(lang string) Catalog {
+	lang = normalizeLang(lang)
+
+	catalogs.RLock()
+	defer catalogs.RUnlock()
+
+	if catalog, ok := catalogs.m[lang]; ok {
+		return catalog
+	}
+	if base, _ := head(lang, "-"); base != lang {
+		if catalog, ok := catalogs.m[base]; ok {
+			return catalog
+		}
+	}
+	return catalogs.m[DefaultLanguage]
+}
+
+func lookupMessage(catalog Catalog, e *FieldError) (string, bool) {
+	if e.Field == "" {
+		return "", false
+	}
+	keys := []string{e.Field + "." + e.Rule, e.Rule + "." + e.Param, e.Rule, "default"}
+	for _, key := range keys {
+		if tpl, ok := catalog[key]; ok {
+			return tpl, true
+		}
+	}
+	return "", false
+}
+
+func normalizeLang(lang string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(lang), "_", "-", -1))
+}
@@ -0,0 +1,79 @@
+package binding
+
+// catalogEN is the bundled English catalog.
+var catalogEN = Catalog{
+	"default":       "{field} is invalid",
+	"required":      "{field} is required",
+	"type":          "{field} must be a valid {param}",
+	"type.int":      "{field} must be an integer",
+	"type.uint":     "{field} must be a non-negative integer",
+	"type.float":    "{field} must be a number",
+	"type.bool":     "{field} must be true or false",
+	"type.time":     "{field} must be a valid time",
+	"type.duration": "{field} must be a valid duration",
+	"email":         "{field} must be a valid email address",
+	"url":           "{field} must be a valid URL",
+	"requrl":        "{field} must be a valid URL",
+	"alpha":         "{field} may only contain letters",
+	"alphanum":      "{field} may only contain letters and numbers",
+	"numeric":       "{field} may only contain digits",
+	"int":           "{field} must be an integer",
+	"float":         "{field} must be a number",
+	"lowercase":     "{field} must be lowercase",
+	"uppercase":     "{field} must be uppercase",
+	"json":          "{field} must be valid JSON",
+	"uuid":          "{field} must be a valid UUID",
+	"ip":            "{field} must be a valid IP address",
+	"ipv4":          "{field} must be a valid IPv4 address",
+	"ipv6":          "{field} must be a valid IPv6 address",
+	"dns":           "{field} must be a valid domain name",
+	"host":          "{field} must be a valid host",
+	"port":          "{field} must be a valid port",
+	"hexadecimal":   "{field} must be hexadecimal",
+	"base64":        "{field} must be base64 encoded",
+	"length":        "{field} length must be between {min} and {max}",
+	"stringlength":  "{field} length must be between {min} and {max}",
+	"runelength":    "{field} length must be between {min} and {max}",
+	"range":         "{field} must be between {min} and {max}",
+	"in":            "{field} must be one of {param}",
+	"matches":       "{field} has an invalid format",
+}
+
+// catalogZH is the bundled Chinese catalog.
+var catalogZH = Catalog{
+	"default":       "{field}无效",
+	"required":      "{field}不能为空",
+	"type":          "{field}必须是有效的{param}",
+	"type.int":      "{field}必须是整数",
+	"type.uint":     "{field}必须是非负整数",
+	"type.float":    "{field}必须是数字",
+	"type.bool":     "{field}必须是 true 或 false",
+	"type.time":     "{field}必须是有效的时间",
+	"type.duration": "{field}必须是有效的时长",
+	"email":         "{field}必须是有效的邮箱地址",
+	"url":           "{field}必须是有效的URL",
+	"requrl":        "{field}必须是有效的URL",
+	"alpha":         "{field}只能包含字母",
+	"alphanum":      "{field}只能包含字母和数字",
+	"numeric":       "{field}只能包含数字",
+	"int":           "{field}必须是整数",
+	"float":         "{field}必须是数字",
+	"lowercase":     "{field}必须是小写",
+	"uppercase":     "{field}必须是大写",
+	"json":          "{field}必须是有效的JSON",
+	"uuid":          "{field}必须是有效的UUID",
+	"ip":            "{field}必须是有效的IP地址",
+	"ipv4":          "{field}必须是有效的IPv4地址",
+	"ipv6":          "{field}必须是有效的IPv6地址",
+	"dns":           "{field}必须是有效的域名",
+	"host":          "{field}必须是有效的主机",
+	"port":          "{field}必须是有效的端口",
+	"hexadecimal":   "{field}必须是十六进制",
+	"base64":        "{field}必须是base64编码",
+	"length":        "{field}长度必须在{min}到{max}之间",
+	"stringlength":  "{field}长度必须在{min}到{max}之间",
+	"runelength":    "{field}长度必须在{min}到{max}之间",
+	"range":         "{field}必须在{min}到{max}之间",
+	"in":            "{field}必须是{param}中的一个",
+	"matches":       "{field}格式不正确",
+}
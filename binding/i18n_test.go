@@ -0,0 +1,103 @@
+package binding
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMatchLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"zh", "zh"},
+		{"zh-TW", "zh"},
+		{"ZH_tw", "zh"},
+		{"zh-CN,zh;q=0.9,en;q=0.8", "zh"},
+		{"en;q=0.5, zh;q=0.8", "zh"},
+		{"fr, zh;q=0.5", "zh"},
+		{"fr, de", "en"},
+		{"*", "en"},
+		{"*, zh;q=0.1", "zh"},
+		{"de, *;q=0.5", "en"},
+		{"zh;q=0, en;q=0.1", "en"},
+		{"zh;q=0", "en"},
+		{"en;q=bad, zh;q=0.9", "en"},
+	}
+	for _, tt := range tests {
+		if got := MatchLanguage(tt.header); got != tt.want {
+			t.Errorf("MatchLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	err := LoadCatalog("x-test", strings.NewReader(`{
+		"required": "{field} fehlt",
+		"field:Name": "Der Name",
+		"Age.range": "{field}: {min}..{max}"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterCatalog("x-test", Catalog{"email": "{value} ist keine E-Mail"})
+
+	tests := []struct {
+		name string
+		err  *FieldError
+		lang string
+		want string
+	}{
+		{"rule", &FieldError{Field: "Name", Rule: "required"}, "en", "Name is required"},
+		{"language", &FieldError{Field: "Name", Rule: "required"}, "zh", "Name不能为空"},
+		{"base language", &FieldError{Field: "Name", Rule: "required"}, "zh-TW", "Name不能为空"},
+		{"unknown language", &FieldError{Field: "Name", Rule: "required"}, "fr", "Name is required"},
+		{"missing key", &FieldError{Field: "Name", Rule: "custom"}, "en", "Name is invalid"},
+		{"missing key in language", &FieldError{Field: "Name", Rule: "custom"}, "zh", "Name无效"},
+		{"missing key and default", &FieldError{Field: "Age", Rule: "custom"}, "x-test", "Age is invalid"},
+		{"no field", &FieldError{Rule: "required", Err: errors.New("raw")}, "en", "raw"},
+		{"rule param", &FieldError{Field: "Age", Rule: "type", Param: "int"}, "en", "Age must be an integer"},
+		{"min and max", &FieldError{Field: "Code", Rule: "length", Param: "1|10"}, "en", "Code length must be between 1 and 10"},
+		{"param list", &FieldError{Field: "Kind", Rule: "in", Param: "a|b|c"}, "en", "Kind must be one of a, b, c"},
+		{"field rule", &FieldError{Field: "Age", Rule: "range", Param: "1|99"}, "x-test", "Age: 1..99"},
+		{"field name", &FieldError{Field: "Name", Rule: "required"}, "x-test", "Der Name fehlt"},
+		{"value", &FieldError{Field: "Mail", Rule: "email", Value: "a@"}, "x-test", "a@ ist keine E-Mail"},
+		{"registered merges", &FieldError{Field: "Mail", Rule: "required"}, "x-test", "Mail fehlt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Translate(tt.err, tt.lang).Error(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if tt.err.Message != "" {
+				t.Error("Translate modified its argument")
+			}
+		})
+	}
+}
+
+func TestTranslateErrors(t *testing.T) {
+	errs := Errors{{Field: "Name", Rule: "required"}, {Field: "Age", Rule: "type", Param: "int"}}
+	if got, want := Translate(errs, "zh").Error(), "Name不能为空;Age必须是整数"; got != want {
+		t.Errorf("Errors: got %q, want %q", got, want)
+	}
+
+	wrapped := fmt.Errorf("bind: %w", &FieldError{Field: "Name", Rule: "required"})
+	if got, want := Translate(wrapped, "en").Error(), "Name is required"; got != want {
+		t.Errorf("wrapped: got %q, want %q", got, want)
+	}
+
+	plain := errors.New("plain")
+	if got := Translate(plain, "zh"); got != plain {
+		t.Errorf("plain error changed to %v", got)
+	}
+}
+
+func TestLoadCatalogInvalid(t *testing.T) {
+	if err := LoadCatalog("x-bad", strings.NewReader(`["not", "an", "object"]`)); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -5,47 +5,106 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 )
 
 // EnableDecoderUseNumber is used to call the UseNumber method on the JSON
 // Decoder instance. UseNumber causes the Decoder to unmarshal a number into an
 // interface{} as a Number instead of as a float64.
+//
+// Deprecated: it affects every Server in the process, use NewJSON with
+// JSONOptions.UseNumber instead.
 var EnableDecoderUseNumber = false
 
 // EnableDecoderDisallowUnknownFields is used to call the DisallowUnknownFields method
 // on the JSON Decoder instance. DisallowUnknownFields causes the Decoder to
 // return an error when the destination is a struct and the input contains object
 // keys which do not match any non-ignored, exported fields in the destination.
+//
+// Deprecated: it affects every Server in the process, use NewJSON with
+// JSONOptions.DisallowUnknownFields instead.
 var EnableDecoderDisallowUnknownFields = false
 
-type jsonBinding struct{}
+// DuplicateKeyPolicy tells the JSON binding how to handle an object which
+// contains the same key more than once.
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateKeyLastWins keeps the last value, like encoding/json does.
+	DuplicateKeyLastWins DuplicateKeyPolicy = iota
+	// DuplicateKeyReject fails the binding.
+	DuplicateKeyReject
+)
+
+// JSONOptions configures the decoder of a JSON binding.
+type JSONOptions struct {
+	// UseNumber unmarshals numbers into an interface{} as a json.Number.
+	UseNumber bool
+	// DisallowUnknownFields rejects object keys which do not match a field.
+	DisallowUnknownFields bool
+	// MaxDepth limits the nesting of objects and arrays, 0 means no limit.
+	MaxDepth int
+	// DuplicateKeys is the policy for duplicate object keys.
+	DuplicateKeys DuplicateKeyPolicy
+}
+
+// NewJSON returns a JSON binding which decodes with opts.
+func NewJSON(opts JSONOptions) BindingBody {
+	return jsonBinding{opts: &opts}
+}
+
+type jsonBinding struct {
+	// opts is nil for the default binding, which falls back to the package variables.
+	opts *JSONOptions
+}
 
 var _ BindingBody = &jsonBinding{}
 
-func (jsonBinding) Bind(req *http.Request, target interface{}) error {
+func (b jsonBinding) Bind(req *http.Request, target interface{}) error {
 	if req == nil || req.Body == nil {
 		return fmt.Errorf("invalid request")
 	}
 	defer req.Body.Close()
 
-	return decodeJSON(req.Body, target)
+	return decodeJSON(req.Body, target, b.options())
 }
 
 func (jsonBinding) Name() string {
 	return "json"
 }
 
-func (jsonBinding) BindBody(body []byte, target interface{}) error {
-	return decodeJSON(bytes.NewReader(body), target)
+func (b jsonBinding) BindBody(body []byte, target interface{}) error {
+	return decodeJSON(bytes.NewReader(body), target, b.options())
+}
+
+func (b jsonBinding) options() JSONOptions {
+	if b.opts != nil {
+		return *b.opts
+	}
+	return JSONOptions{
+		UseNumber:             EnableDecoderUseNumber,
+		DisallowUnknownFields: EnableDecoderDisallowUnknownFields,
+	}
 }
 
-func decodeJSON(r io.Reader, target interface{}) error {
+func decodeJSON(r io.Reader, target interface{}, opts JSONOptions) error {
+	if opts.MaxDepth > 0 || opts.DuplicateKeys == DuplicateKeyReject {
+		body, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if err := checkJSON(body, opts); err != nil {
+			return err
+		}
+		r = bytes.NewReader(body)
+	}
+
 	dec := json.NewDecoder(r)
-	if EnableDecoderDisallowUnknownFields {
+	if opts.DisallowUnknownFields {
 		dec.DisallowUnknownFields()
 	}
-	if EnableDecoderUseNumber {
+	if opts.UseNumber {
 		dec.UseNumber()
 	}
 
@@ -55,3 +114,66 @@ func decodeJSON(r io.Reader, target interface{}) error {
 
 	return validate(target)
 }
+
+// checkJSON walks the tokens of body and enforces the depth and duplicate
+// key options. Syntax errors are left to the decoder.
+func checkJSON(body []byte, opts JSONOptions) error {
+	type frame struct {
+		keys      map[string]bool // nil for arrays
+		expectKey bool
+	}
+
+	if opts.MaxDepth <= 0 && opts.DuplicateKeys != DuplicateKeyReject {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	var stack []*frame
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+
+		var top *frame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+
+		switch v := tok.(type) {
+		case json.Delim:
+			switch v {
+			case '{', '[':
+				if top != nil && top.keys != nil {
+					top.expectKey = true
+				}
+				if opts.MaxDepth > 0 && len(stack) >= opts.MaxDepth {
+					return fmt.Errorf("json: exceeded max nesting depth %d", opts.MaxDepth)
+				}
+				f := &frame{}
+				if v == '{' {
+					f.keys, f.expectKey = make(map[string]bool), true
+				}
+				stack = append(stack, f)
+			default:
+				stack = stack[:len(stack)-1]
+			}
+		case string:
+			if top != nil && top.keys != nil && top.expectKey {
+				if opts.DuplicateKeys == DuplicateKeyReject && top.keys[v] {
+					return fmt.Errorf("json: duplicate key %q", v)
+				}
+				top.keys[v] = true
+				top.expectKey = false
+				continue
+			}
+			if top != nil && top.keys != nil {
+				top.expectKey = true
+			}
+		default:
+			if top != nil && top.keys != nil {
+				top.expectKey = true
+			}
+		}
+	}
+}
@@ -0,0 +1,557 @@
+package binding
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Patch records the fields touched by a JSON Patch or JSON Merge Patch.
+// Paths are JSON pointers like "/address/city"; dotted paths like
+// "address.city" are accepted by the methods too.
+type Patch struct {
+	// paths maps the touched paths to whether they were set to null or removed.
+	paths map[string]bool
+}
+
+// Has reports whether the patch touched path or a path below it,
+// so a field set to null is present while an absent one is not.
+func (p *Patch) Has(path string) bool {
+	path = pointerPath(path)
+	if _, ok := p.paths[path]; ok {
+		return true
+	}
+	for touched := range p.paths {
+		if strings.HasPrefix(touched, path+"/") || path == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNull reports whether the patch set path to null or removed it.
+func (p *Patch) IsNull(path string) bool {
+	return p.paths[pointerPath(path)]
+}
+
+// Paths returns the touched paths in sorted order.
+func (p *Patch) Paths() []string {
+	paths := make([]string, 0, len(p.paths))
+	for path := range p.paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (p *Patch) touch(tokens []string, null bool) {
+	if p.paths == nil {
+		p.paths = make(map[string]bool)
+	}
+	p.paths[formatPointer(tokens)] = null
+}
+
+// pointerPath turns a dotted path like "address.city" into a JSON pointer.
+func pointerPath(path string) string {
+	if path == "" || path[0] == '/' {
+		return path
+	}
+	return formatPointer(strings.Split(path, "."))
+}
+
+func formatPointer(tokens []string) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		b.WriteByte('/')
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(t))
+	}
+	return b.String()
+}
+
+// PatchOperation is one operation of a JSON Patch document (RFC 6902).
+type PatchOperation struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	From string `json:"from,omitempty"`
+	// Value is the raw JSON value, "null" included, nil when it is missing.
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ApplyJSONPatch applies a JSON Patch document (RFC 6902) to target, which
+// must be a pointer, and validates the result.
+// Fields of target which are not serialized to JSON keep their values.
+func ApplyJSONPatch(patch []byte, target interface{}) (*Patch, error) {
+	return ApplyJSONPatchWithOptions(patch, target, JSONOptions{})
+}
+
+// ApplyJSONPatchWithOptions is ApplyJSONPatch with the limits of opts checked
+// on the patch and its other options used to decode the result into target.
+func ApplyJSONPatchWithOptions(patch []byte, target interface{}, opts JSONOptions) (*Patch, error) {
+	if err := checkJSON(patch, opts); err != nil {
+		return nil, err
+	}
+	var ops []PatchOperation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, err
+	}
+
+	doc, err := toDocument(target)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Patch{}
+	for i, op := range ops {
+		if doc, err = applyOperation(doc, op, result); err != nil {
+			return nil, fmt.Errorf("json patch operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	if err := fromDocument(doc, target, opts); err != nil {
+		return nil, err
+	}
+	return result, validate(target)
+}
+
+// ApplyMergePatch applies a JSON Merge Patch document (RFC 7396) to target,
+// which must be a pointer, and validates the result.
+// Fields of target which are not serialized to JSON keep their values.
+func ApplyMergePatch(patch []byte, target interface{}) (*Patch, error) {
+	return ApplyMergePatchWithOptions(patch, target, JSONOptions{})
+}
+
+// ApplyMergePatchWithOptions is ApplyMergePatch with the limits of opts checked
+// on the patch and its other options used to decode the result into target.
+func ApplyMergePatchWithOptions(patch []byte, target interface{}, opts JSONOptions) (*Patch, error) {
+	if err := checkJSON(patch, opts); err != nil {
+		return nil, err
+	}
+	var p interface{}
+	if err := unmarshalDocument(patch, &p); err != nil {
+		return nil, err
+	}
+
+	doc, err := toDocument(target)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Patch{}
+	doc = mergePatch(doc, p, nil, result)
+
+	if err := fromDocument(doc, target, opts); err != nil {
+		return nil, err
+	}
+	return result, validate(target)
+}
+
+func mergePatch(doc, patch interface{}, tokens []string, result *Patch) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		result.touch(tokens, patch == nil)
+		return patch
+	}
+
+	docObj, ok := doc.(map[string]interface{})
+	if !ok {
+		docObj = make(map[string]interface{})
+	}
+	for key, value := range patchObj {
+		path := append(tokens[:len(tokens):len(tokens)], key)
+		if value == nil {
+			delete(docObj, key)
+			result.touch(path, true)
+			continue
+		}
+		docObj[key] = mergePatch(docObj[key], value, path, result)
+	}
+	return docObj
+}
+
+func applyOperation(doc interface{}, op PatchOperation, result *Patch) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, errors.New("missing value")
+		}
+		if err := unmarshalDocument(op.Value, &value); err != nil {
+			return nil, err
+		}
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = getPointer(doc, from); err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if strings.HasPrefix(op.Path+"/", op.From+"/") && op.Path != op.From {
+				return nil, errors.New("can not move a value into one of its children")
+			}
+			if doc, err = updatePointer(doc, from, removeChild); err != nil {
+				return nil, err
+			}
+			result.touch(from, true)
+		} else {
+			value = deepCopy(value)
+		}
+	}
+
+	if len(path) == 0 && op.Op != "test" {
+		if op.Op == "remove" {
+			return nil, errors.New("can not remove the whole document")
+		}
+		result.touch(path, value == nil)
+		return value, nil
+	}
+
+	switch op.Op {
+	case "add", "move", "copy":
+		result.touch(path, value == nil)
+		return updatePointer(doc, path, addChild(value))
+	case "replace":
+		result.touch(path, value == nil)
+		return updatePointer(doc, path, replaceChild(value))
+	case "remove":
+		result.touch(path, true)
+		return updatePointer(doc, path, removeChild)
+	case "test":
+		current, err := getPointer(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equalDocuments(current, value) {
+			return nil, errors.New("test failed")
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q", op.Op)
+}
+
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("invalid json pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(tokens[i])
+	}
+	return tokens, nil
+}
+
+// childFunc changes the child key of the container parent and returns the
+// new container.
+type childFunc func(parent interface{}, key string) (interface{}, error)
+
+// updatePointer calls fn on the parent of the value at tokens.
+func updatePointer(doc interface{}, tokens []string, fn childFunc) (interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, errors.New("can not change the whole document")
+	}
+	if len(tokens) == 1 {
+		return fn(doc, tokens[0])
+	}
+
+	child, err := getChild(doc, tokens[0])
+	if err != nil {
+		return nil, err
+	}
+	child, err = updatePointer(child, tokens[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+	return replaceChild(child)(doc, tokens[0])
+}
+
+func getPointer(doc interface{}, tokens []string) (interface{}, error) {
+	for _, token := range tokens {
+		var err error
+		if doc, err = getChild(doc, token); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+func getChild(doc interface{}, key string) (interface{}, error) {
+	switch c := doc.(type) {
+	case map[string]interface{}:
+		if v, ok := c[key]; ok {
+			return v, nil
+		}
+	case []interface{}:
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(c) {
+			return c[i], nil
+		}
+	}
+	return nil, fmt.Errorf("path %q does not exist", key)
+}
+
+func addChild(value interface{}) childFunc {
+	return func(parent interface{}, key string) (interface{}, error) {
+		switch c := parent.(type) {
+		case map[string]interface{}:
+			c[key] = value
+			return c, nil
+		case []interface{}:
+			if key == "-" {
+				return append(c, value), nil
+			}
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i > len(c) {
+				return nil, fmt.Errorf("index %q out of range", key)
+			}
+			c = append(c, nil)
+			copy(c[i+1:], c[i:])
+			c[i] = value
+			return c, nil
+		}
+		return nil, fmt.Errorf("path %q does not exist", key)
+	}
+}
+
+func replaceChild(value interface{}) childFunc {
+	return func(parent interface{}, key string) (interface{}, error) {
+		if _, err := getChild(parent, key); err != nil {
+			return nil, err
+		}
+		switch c := parent.(type) {
+		case map[string]interface{}:
+			c[key] = value
+			return c, nil
+		case []interface{}:
+			i, _ := strconv.Atoi(key)
+			c[i] = value
+			return c, nil
+		}
+		return nil, fmt.Errorf("path %q does not exist", key)
+	}
+}
+
+func removeChild(parent interface{}, key string) (interface{}, error) {
+	if _, err := getChild(parent, key); err != nil {
+		return nil, err
+	}
+	switch c := parent.(type) {
+	case map[string]interface{}:
+		delete(c, key)
+		return c, nil
+	case []interface{}:
+		i, _ := strconv.Atoi(key)
+		return append(c[:i], c[i+1:]...), nil
+	}
+	return nil, fmt.Errorf("path %q does not exist", key)
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch c := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(c))
+		for k, v := range c {
+			m[k] = deepCopy(v)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(c))
+		for i, v := range c {
+			s[i] = deepCopy(v)
+		}
+		return s
+	}
+	return v
+}
+
+func equalDocuments(a, b interface{}) bool {
+	if na, ok := a.(json.Number); ok {
+		if nb, ok := b.(json.Number); ok {
+			return equalNumbers(na, nb)
+		}
+	}
+	switch ca := a.(type) {
+	case map[string]interface{}:
+		cb, ok := b.(map[string]interface{})
+		if !ok || len(ca) != len(cb) {
+			return false
+		}
+		for k, v := range ca {
+			if w, ok := cb[k]; !ok || !equalDocuments(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		cb, ok := b.([]interface{})
+		if !ok || len(ca) != len(cb) {
+			return false
+		}
+		for i := range ca {
+			if !equalDocuments(ca[i], cb[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// equalNumbers compares numbers exactly for integers of up to 77 digits, so
+// large IDs which are equal as float64 do not pass a "test" operation.
+func equalNumbers(a, b json.Number) bool {
+	if a == b {
+		return true
+	}
+	fa, _, errA := big.ParseFloat(string(a), 10, 256, big.ToNearestEven)
+	fb, _, errB := big.ParseFloat(string(b), 10, 256, big.ToNearestEven)
+	return errA == nil && errB == nil && fa.Cmp(fb) == 0
+}
+
+func unmarshalDocument(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func toDocument(target interface{}) (interface{}, error) {
+	if v := reflect.ValueOf(target); v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil, errors.New("patch target must be a non-nil pointer")
+	}
+	data, err := json.Marshal(target)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	return doc, unmarshalDocument(data, &doc)
+}
+
+// fromDocument decodes doc into a zero value of target's type, so removed
+// fields do not keep their old values, and stores it in target. Fields which
+// are not serialized to JSON, like unexported ones, are kept from target.
+func fromDocument(doc interface{}, target interface{}, opts JSONOptions) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	v := reflect.ValueOf(target).Elem()
+	fresh := reflect.New(v.Type())
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if opts.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if opts.UseNumber {
+		dec.UseNumber()
+	}
+	if err := dec.Decode(fresh.Interface()); err != nil {
+		return err
+	}
+
+	result := reflect.New(v.Type()).Elem()
+	result.Set(v)
+	setVisible(result, fresh.Elem())
+	v.Set(result)
+	return nil
+}
+
+var (
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// setVisible sets the fields of dst which are serialized to JSON from fresh.
+// Structs are merged field by field, pointed to structs are copied first, so
+// the original value is never changed.
+func setVisible(dst, fresh reflect.Value) {
+	t := dst.Type()
+	switch {
+	case t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonUnmarshalerType):
+	case t.Kind() == reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.PkgPath != "" && !sf.Anonymous || sf.Tag.Get("json") == "-" {
+				continue
+			}
+			if f := dst.Field(i); f.CanSet() || f.Kind() == reflect.Struct {
+				setVisible(f, fresh.Field(i))
+			}
+		}
+		return
+	case t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct:
+		if !dst.IsNil() && !fresh.IsNil() {
+			p := reflect.New(t.Elem())
+			p.Elem().Set(dst.Elem())
+			setVisible(p.Elem(), fresh.Elem())
+			dst.Set(p)
+			return
+		}
+	}
+	if dst.CanSet() {
+		dst.Set(fresh)
+	}
+}
+
+type jsonPatchBinding struct{}
+
+var _ BindingBody = jsonPatchBinding{}
+
+func (jsonPatchBinding) Name() string {
+	return "json-patch"
+}
+
+func (b jsonPatchBinding) Bind(req *http.Request, target interface{}) error {
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	return b.BindBody(body, target)
+}
+
+func (jsonPatchBinding) BindBody(body []byte, target interface{}) error {
+	_, err := ApplyJSONPatch(body, target)
+	return err
+}
+
+type mergePatchBinding struct{}
+
+var _ BindingBody = mergePatchBinding{}
+
+func (mergePatchBinding) Name() string {
+	return "merge-patch"
+}
+
+func (b mergePatchBinding) Bind(req *http.Request, target interface{}) error {
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	return b.BindBody(body, target)
+}
+
+func (mergePatchBinding) BindBody(body []byte, target interface{}) error {
+	_, err := ApplyMergePatch(body, target)
+	return err
+}
+
+func readBody(req *http.Request) ([]byte, error) {
+	if req == nil || req.Body == nil {
+		return nil, fmt.Errorf("invalid request")
+	}
+	defer req.Body.Close()
+	return ioutil.ReadAll(req.Body)
+}
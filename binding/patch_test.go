@@ -0,0 +1,253 @@
+package binding
+
+import (
+	"strings"
+	"testing"
+)
+
+type patchUser struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestApplyPatchWithOptions(t *testing.T) {
+	strict := JSONOptions{DisallowUnknownFields: true, MaxDepth: 2}
+	tests := []struct {
+		name    string
+		merge   bool
+		patch   string
+		wantErr string
+	}{
+		{"merge known field", true, `{"name":"b"}`, ""},
+		{"merge unknown field", true, `{"nick":"b"}`, "unknown field"},
+		{"merge too deep", true, `{"name":{"a":{"b":1}}}`, "depth"},
+		{"json patch known field", false, `[{"op":"replace","path":"/name","value":"b"}]`, ""},
+		{"json patch unknown field", false, `[{"op":"add","path":"/nick","value":"b"}]`, "unknown field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := patchUser{Name: "a", Age: 3}
+			var err error
+			if tt.merge {
+				_, err = ApplyMergePatchWithOptions([]byte(tt.patch), &u, strict)
+			} else {
+				_, err = ApplyJSONPatchWithOptions([]byte(tt.patch), &u, strict)
+			}
+			if tt.wantErr == "" {
+				if err != nil || u.Name != "b" {
+					t.Fatalf("got %+v, %v", u, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type patchAddress struct {
+	City   string `json:"city"`
+	Street string `json:"street,omitempty"`
+	geo    string
+}
+
+type patchAccount struct {
+	ID           int64  `json:"-"`
+	PasswordHash string `json:"-"`
+	Name         string `json:"name"`
+	Nick         string `json:"nick,omitempty"`
+	Balance      int64  `json:"balance"`
+	Address      *patchAddress
+	version      int
+}
+
+func TestApplyPatchKeepsHiddenFields(t *testing.T) {
+	addr := &patchAddress{City: "a", Street: "s", geo: "1,2"}
+	acc := patchAccount{ID: 7, PasswordHash: "h", Name: "n", Nick: "k", Address: addr, version: 3}
+
+	if _, err := ApplyMergePatch([]byte(`{"nick":null,"Address":{"street":null,"city":"b"}}`), &acc); err != nil {
+		t.Fatal(err)
+	}
+	if acc.ID != 7 || acc.PasswordHash != "h" || acc.version != 3 {
+		t.Errorf("hidden fields changed: %+v", acc)
+	}
+	if acc.Nick != "" || acc.Name != "n" {
+		t.Errorf("visible fields = %q %q, want removed nick", acc.Name, acc.Nick)
+	}
+	if acc.Address.City != "b" || acc.Address.Street != "" || acc.Address.geo != "1,2" {
+		t.Errorf("Address = %+v", *acc.Address)
+	}
+	if *addr != (patchAddress{City: "a", Street: "s", geo: "1,2"}) {
+		t.Errorf("original address changed: %+v", *addr)
+	}
+}
+
+func TestApplyJSONPatchTestNumbers(t *testing.T) {
+	tests := []struct {
+		balance int64
+		value   string
+		ok      bool
+	}{
+		{9007199254740993, "9007199254740993", true},
+		{9007199254740993, "9007199254740992", false},
+		{1, "1.0", true},
+		{1, "1e0", true},
+		{1, "2", false},
+	}
+	for _, tt := range tests {
+		acc := patchAccount{Balance: tt.balance}
+		patch := `[{"op":"test","path":"/balance","value":` + tt.value + `}]`
+		_, err := ApplyJSONPatch([]byte(patch), &acc)
+		if (err == nil) != tt.ok {
+			t.Errorf("test %d against %s: error = %v, want ok %v", tt.balance, tt.value, err, tt.ok)
+		}
+	}
+}
+
+type patchProfile struct {
+	Name    *string       `json:"name"`
+	Bio     string        `json:"bio"`
+	Address *patchAddress `json:"address"`
+	Slash   string        `json:"a/b"`
+	Tilde   string        `json:"m~n"`
+}
+
+func TestPatchPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		merge   bool
+		patch   string
+		paths   []string
+		has     []string
+		absent  []string
+		null    []string
+		notNull []string
+	}{
+		{
+			name: "merge null", merge: true, patch: `{"name":null}`,
+			paths: []string{"/name"}, has: []string{"name", "/name"}, absent: []string{"bio", "address"},
+			null: []string{"name"},
+		},
+		{
+			name: "merge value", merge: true, patch: `{"bio":"x"}`,
+			paths: []string{"/bio"}, has: []string{"bio"}, absent: []string{"name"},
+			notNull: []string{"bio", "name"},
+		},
+		{
+			name: "merge nested object", merge: true, patch: `{"address":{"city":"x","street":null}}`,
+			paths:   []string{"/address/city", "/address/street"},
+			has:     []string{"address", "address.city", "/address/city", "address.street"},
+			absent:  []string{"address.geo", "name"},
+			null:    []string{"address.street"},
+			notNull: []string{"address", "address.city"},
+		},
+		{
+			name: "merge null object", merge: true, patch: `{"address":null}`,
+			paths: []string{"/address"}, has: []string{"address"}, absent: []string{"address.city"},
+			null: []string{"address"},
+		},
+		{
+			name: "merge escaped keys", merge: true, patch: `{"a/b":"1","m~n":null}`,
+			paths: []string{"/a~1b", "/m~0n"}, has: []string{"/a~1b", "a/b", "/m~0n", "m~n"},
+			absent: []string{"a", "/a/b", "m"},
+			null:   []string{"m~n", "/m~0n"}, notNull: []string{"a/b"},
+		},
+		{
+			name: "merge empty", merge: true, patch: `{}`,
+			paths: []string{}, absent: []string{"", "name"},
+		},
+		{
+			name: "remove", patch: `[{"op":"remove","path":"/bio"}]`,
+			paths: []string{"/bio"}, has: []string{"bio"}, absent: []string{"name"},
+			null: []string{"bio"},
+		},
+		{
+			name: "replace with null", patch: `[{"op":"replace","path":"/name","value":null}]`,
+			paths: []string{"/name"}, has: []string{"name"},
+			null: []string{"name"},
+		},
+		{
+			name: "move", patch: `[{"op":"move","from":"/bio","path":"/a~1b"}]`,
+			paths: []string{"/a~1b", "/bio"}, has: []string{"bio", "a/b"}, absent: []string{"name"},
+			null: []string{"bio"}, notNull: []string{"a/b"},
+		},
+		{
+			name: "copy", patch: `[{"op":"copy","from":"/bio","path":"/m~0n"}]`,
+			paths: []string{"/m~0n"}, has: []string{"m~n"}, absent: []string{"bio"},
+			notNull: []string{"m~n"},
+		},
+		{
+			name: "add nested", patch: `[{"op":"add","path":"/address/city","value":"y"}]`,
+			paths: []string{"/address/city"}, has: []string{"address", "address.city"}, absent: []string{"address.street"},
+			notNull: []string{"address.city"},
+		},
+		{
+			name: "test only", patch: `[{"op":"test","path":"/bio","value":"old"}]`,
+			paths: []string{}, absent: []string{"bio"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := "n"
+			target := patchProfile{Name: &name, Bio: "old", Address: &patchAddress{City: "c"}}
+			var (
+				p   *Patch
+				err error
+			)
+			if tt.merge {
+				p, err = ApplyMergePatch([]byte(tt.patch), &target)
+			} else {
+				p, err = ApplyJSONPatch([]byte(tt.patch), &target)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.Paths(); strings.Join(got, " ") != strings.Join(tt.paths, " ") {
+				t.Errorf("Paths() = %q, want %q", got, tt.paths)
+			}
+			for _, path := range tt.has {
+				if !p.Has(path) {
+					t.Errorf("Has(%q) = false", path)
+				}
+			}
+			for _, path := range tt.absent {
+				if p.Has(path) {
+					t.Errorf("Has(%q) = true", path)
+				}
+			}
+			for _, path := range tt.null {
+				if !p.IsNull(path) {
+					t.Errorf("IsNull(%q) = false", path)
+				}
+			}
+			for _, path := range tt.notNull {
+				if p.IsNull(path) {
+					t.Errorf("IsNull(%q) = true", path)
+				}
+			}
+		})
+	}
+}
+
+func TestPatchNullVersusAbsent(t *testing.T) {
+	name := "n"
+	target := patchProfile{Name: &name, Bio: "old"}
+	p, err := ApplyMergePatch([]byte(`{"name":null}`), &target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.Name != nil || target.Bio != "old" {
+		t.Errorf("got name %v bio %q, want nil name and unchanged bio", target.Name, target.Bio)
+	}
+	if !p.Has("name") || !p.IsNull("name") || p.Has("bio") || p.IsNull("bio") {
+		t.Errorf("paths %v do not tell null name from absent bio", p.Paths())
+	}
+}
+
+func TestApplyJSONPatchMissingValue(t *testing.T) {
+	var target patchProfile
+	if _, err := ApplyJSONPatch([]byte(`[{"op":"add","path":"/bio"}]`), &target); err == nil || !strings.Contains(err.Error(), "missing value") {
+		t.Errorf("error = %v, want missing value", err)
+	}
+}
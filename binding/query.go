@@ -0,0 +1,16 @@
+package binding
+
+import "net/http"
+
+type queryBinding struct{}
+
+func (queryBinding) Name() string {
+	return "query"
+}
+
+func (queryBinding) Bind(req *http.Request, obj interface{}) error {
+	if err := mapForm(obj, req.URL.Query()); err != nil {
+		return err
+	}
+	return validate(obj)
+}
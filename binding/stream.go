@@ -0,0 +1,160 @@
+package binding
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RecordError is the error of one record of a JSON stream.
+type RecordError struct {
+	Index int
+	Err   error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("record %d: %v", e.Index, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// MaxRecordErrors limits the invalid records collected by a stream decoding,
+// which stops after that many.
+var MaxRecordErrors = 100
+
+// RecordErrors is a list of record errors of a JSON stream.
+type RecordErrors []*RecordError
+
+func (es RecordErrors) Error() string {
+	msgs := make([]string, 0, len(es))
+	for _, e := range es {
+		msgs = append(msgs, e.Error())
+	}
+	return strings.Join(msgs, ";")
+}
+
+// StreamDecoder decodes the elements of a JSON array or the values of
+// newline-delimited JSON one at a time, so large bodies are never held in memory.
+type StreamDecoder struct {
+	dec     *json.Decoder
+	src     *streamReader
+	opts    JSONOptions
+	ndjson  bool
+	started bool
+	done    bool
+	index   int
+}
+
+// NewStreamDecoder returns a StreamDecoder reading from r. With ndjson the
+// input is a sequence of JSON values, otherwise it is a single JSON array.
+// opts.MaxDepth and opts.DuplicateKeys are checked on each record.
+func NewStreamDecoder(r io.Reader, ndjson bool, opts JSONOptions) *StreamDecoder {
+	src := &streamReader{r: r}
+	dec := json.NewDecoder(src)
+	if opts.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if opts.UseNumber {
+		dec.UseNumber()
+	}
+	return &StreamDecoder{dec: dec, src: src, opts: opts, ndjson: ndjson, index: -1}
+}
+
+// streamReader remembers the read error, which breaks the stream.
+type streamReader struct {
+	r   io.Reader
+	err error
+}
+
+func (s *streamReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if err != nil && err != io.EOF {
+		s.err = err
+	}
+	return n, err
+}
+
+// Index returns the index of the last record returned by Next.
+func (d *StreamDecoder) Index() int {
+	return d.index
+}
+
+// Next decodes the next record into target and validates it. It returns
+// io.EOF after the last record.
+// A *RecordError means only this record is invalid and Next can be called
+// again; any other error means the stream is broken.
+func (d *StreamDecoder) Next(target interface{}) error {
+	if d.done {
+		return io.EOF
+	}
+
+	if !d.ndjson {
+		if !d.started {
+			d.started = true
+			tok, err := d.dec.Token()
+			if err != nil {
+				return err
+			}
+			if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+				return fmt.Errorf("json stream: expected array, got %v", tok)
+			}
+		}
+		if !d.dec.More() {
+			d.done = true
+			if _, err := d.dec.Token(); err != nil {
+				return err
+			}
+			return io.EOF
+		}
+	}
+
+	d.index++
+	if err := d.decode(target); err != nil {
+		if rerr, ok := err.(*RecordError); ok {
+			return rerr
+		}
+		if err == io.EOF && d.ndjson {
+			d.done = true
+			return io.EOF
+		}
+		// the decoder reads a whole value before it unmarshals, so only
+		// syntax and read errors leave it in the middle of the input
+		if _, ok := err.(*json.SyntaxError); ok || err == io.ErrUnexpectedEOF || d.src.err != nil {
+			return fmt.Errorf("json stream: record %d: %w", d.index, err)
+		}
+		return &RecordError{Index: d.index, Err: err}
+	}
+
+	if err := validate(target); err != nil {
+		return &RecordError{Index: d.index, Err: err}
+	}
+	return nil
+}
+
+// decode decodes the next record into target. With depth or duplicate key
+// checks the record is read raw first, so it can be checked before decoding.
+func (d *StreamDecoder) decode(target interface{}) error {
+	if d.opts.MaxDepth <= 0 && d.opts.DuplicateKeys != DuplicateKeyReject {
+		return d.dec.Decode(target)
+	}
+
+	var raw json.RawMessage
+	if err := d.dec.Decode(&raw); err != nil {
+		return err
+	}
+	if err := checkJSON(raw, d.opts); err != nil {
+		return &RecordError{Index: d.index, Err: err}
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if d.opts.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if d.opts.UseNumber {
+		dec.UseNumber()
+	}
+	return dec.Decode(target)
+}
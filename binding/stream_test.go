@@ -0,0 +1,62 @@
+package binding
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+type streamRecord struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestStreamDecoderRecordErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		ndjson     bool
+		opts       JSONOptions
+		wantValid  int
+		wantRecord int
+		wantBroken bool
+	}{
+		{"type error", `[{"id":1},{"id":"x"},{"id":3}]`, false, JSONOptions{}, 2, 1, false},
+		{"unknown field", "{\"id\":1}\n{\"id\":2,\"x\":1}\n{\"id\":3}\n", true, JSONOptions{DisallowUnknownFields: true}, 2, 1, false},
+		{"syntax error", `[{"id":1},{"id":}]`, false, JSONOptions{}, 1, 0, true},
+		{"truncated", `[{"id":1},{"id":2`, false, JSONOptions{}, 1, 0, true},
+		{"too deep", `[{"id":1},{"id":2,"name":{"a":{"b":1}}},{"id":3}]`, false, JSONOptions{MaxDepth: 2}, 2, 1, false},
+		{"too deep ndjson", "{\"id\":1}\n[[[1]]]\n{\"id\":3}\n", true, JSONOptions{MaxDepth: 2}, 2, 1, false},
+		{"duplicate key", `[{"id":1},{"id":2,"id":3},{"id":4}]`, false, JSONOptions{DuplicateKeys: DuplicateKeyReject}, 2, 1, false},
+		{"duplicate key allowed", `[{"id":1},{"id":2,"id":3}]`, false, JSONOptions{}, 2, 0, false},
+		{"strict unknown field", `[{"id":1,"x":1},{"id":2}]`, false, JSONOptions{MaxDepth: 4, DisallowUnknownFields: true}, 1, 1, false},
+		{"strict syntax error", `[{"id":1},{"id":}]`, false, JSONOptions{MaxDepth: 4}, 1, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewStreamDecoder(strings.NewReader(tt.input), tt.ndjson, tt.opts)
+			var valid, records int
+			var broken bool
+			for {
+				var r streamRecord
+				err := dec.Next(&r)
+				if err == io.EOF {
+					break
+				}
+				if _, ok := err.(*RecordError); ok {
+					records++
+					continue
+				}
+				if err != nil {
+					broken = true
+					break
+				}
+				valid++
+			}
+			if valid != tt.wantValid || records != tt.wantRecord || broken != tt.wantBroken {
+				t.Errorf("valid %d, record errors %d, broken %v; want %d, %d, %v",
+					valid, records, broken, tt.wantValid, tt.wantRecord, tt.wantBroken)
+			}
+		})
+	}
+}
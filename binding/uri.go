@@ -0,0 +1,25 @@
+package binding
+
+import (
+	"errors"
+	"net/http"
+)
+
+type uriBinding struct{}
+
+var _ BindingUri = uriBinding{}
+
+func (uriBinding) Name() string {
+	return "uri"
+}
+
+func (uriBinding) Bind(req *http.Request, obj interface{}) error {
+	return errors.New("uri binding needs the route params, use BindUri")
+}
+
+func (uriBinding) BindUri(params map[string][]string, obj interface{}) error {
+	if err := mapFormByTag(obj, params, "uri"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
@@ -1,13 +1,28 @@
 package binding
 
-import "github.com/asaskevich/govalidator"
+import (
+	"reflect"
+
+	"github.com/asaskevich/govalidator"
+)
 
 type defaultValidator struct {
 }
 
 func (d *defaultValidator) ValidateStruct(target interface{}) error {
-	_, err := govalidator.ValidateStruct(target)
-	return err
+	// records of a stream may be maps or plain values, which have no tags
+	v := reflect.ValueOf(target)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	if _, err := govalidator.ValidateStruct(target); err != nil {
+		return fromValidatorError(err)
+	}
+	return nil
 }
 
 func (d *defaultValidator) Engine() interface{} {
@@ -0,0 +1,120 @@
+package slimgo
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gitwillsky/slimgo/binding"
+)
+
+// ErrBodyTooLarge is returned when reading a request body beyond its size limit.
+var ErrBodyTooLarge = errors.New("http: request body too large")
+
+// BodyBytesKey is the Data key of the request body cached by ShouldBindBodyWith.
+const BodyBytesKey = "_slimgo/bodybyteskey"
+
+// JSONOptionsKey is the Data key of the JSON decoder options set by JSONDecoderOptions.
+const JSONOptionsKey = "_slimgo/jsonoptionskey"
+
+// limitedBody is a request body which fails with ErrBodyTooLarge after limit bytes.
+type limitedBody struct {
+	io.ReadCloser
+	limit int64
+	read  int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.read > b.limit {
+		return 0, ErrBodyTooLarge
+	}
+	if max := b.limit - b.read + 1; int64(len(p)) > max {
+		p = p[:max]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if b.read > b.limit {
+		return n - int(b.read-b.limit), ErrBodyTooLarge
+	}
+	return n, err
+}
+
+// exceeded reports whether a read went beyond the limit.
+func (b *limitedBody) exceeded() bool {
+	return b.read > b.limit
+}
+
+// bodyReplay is a request body with bytes already read put back in front.
+type bodyReplay struct {
+	io.Reader
+	io.Closer
+}
+
+// replayBody puts read, the bytes read from the body of req so far, back in
+// front of it, so the body can be read again from its start.
+func replayBody(req *http.Request, read []byte) {
+	if lb, ok := req.Body.(*limitedBody); ok {
+		lb.ReadCloser = bodyReplay{io.MultiReader(bytes.NewReader(read), lb.ReadCloser), lb.ReadCloser}
+		lb.read -= int64(len(read))
+		return
+	}
+	req.Body = bodyReplay{io.MultiReader(bytes.NewReader(read), req.Body), req.Body}
+}
+
+// limitRequestBody limits the body of req to n bytes. It reports false when
+// the Content-Length is already known to exceed the limit.
+// A body which is limited already keeps the lower of both limits.
+func limitRequestBody(req *http.Request, n int64) bool {
+	if req.Body != nil && req.Body != http.NoBody {
+		if lb, ok := req.Body.(*limitedBody); ok {
+			if n < lb.limit {
+				lb.limit = n
+			}
+		} else {
+			req.Body = &limitedBody{ReadCloser: req.Body, limit: n}
+		}
+	}
+	return req.ContentLength <= n
+}
+
+// MaxBodySize returns a middleware which limits the request body to n bytes.
+// Requests with a larger Content-Length get 413 right away, bodies without a
+// length get 413 from the Bind method which reads beyond the limit.
+// It can only lower the server-wide limit set by Server.SetMaxBodySize.
+func MaxBodySize(n int64) Handler {
+	return func(c Context) {
+		if !limitRequestBody(c.Request(), n) {
+			bodyTooLarge(c)
+		}
+	}
+}
+
+func bodyTooLarge(c Context) {
+	c.String(http.StatusRequestEntityTooLarge, http.StatusText(http.StatusRequestEntityTooLarge))
+}
+
+// checkBodySize answers 413 and returns ErrBodyTooLarge when the bind error
+// err came from reading the body beyond its limit. Decoders do not always
+// wrap the read error, so the body itself is asked too.
+func (c *context) checkBodySize(err error) error {
+	if err == nil {
+		return nil
+	}
+	lb, _ := c.request.Body.(*limitedBody)
+	if !errors.Is(err, ErrBodyTooLarge) && (lb == nil || !lb.exceeded()) {
+		return err
+	}
+	if !c.response.Written() {
+		bodyTooLarge(c)
+	}
+	return ErrBodyTooLarge
+}
+
+// JSONDecoderOptions returns a middleware which makes Context.Bind decode JSON
+// bodies with opts, e.g. strict for a public API group and lenient for webhooks.
+func JSONDecoderOptions(opts binding.JSONOptions) Handler {
+	return func(c Context) {
+		c.PutData(JSONOptionsKey, opts)
+	}
+}
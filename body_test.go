@@ -0,0 +1,74 @@
+package slimgo
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gitwillsky/slimgo/binding"
+)
+
+func TestBodySizeLimit(t *testing.T) {
+	s := New()
+	s.SetMode(Release)
+	s.SetMaxBodySize(16)
+	var seen int
+	s.Use(func(c Context) {
+		c.Next()
+		seen = c.ResponseWriter().(statusWriter).Status()
+	})
+	s.POST("/bind", func(c Context) {
+		var v struct {
+			Name string `json:"name"`
+		}
+		if err := c.Bind(&v); err != nil {
+			if !c.ResponseWriter().Written() {
+				c.String(http.StatusBadRequest, err.Error())
+			}
+			return
+		}
+		c.String(http.StatusOK, v.Name)
+	})
+	s.POST("/body", func(c Context) {
+		var v struct {
+			Name string `json:"name"`
+		}
+		if err := c.ShouldBindBodyWith(&v, binding.JSON); err != nil && !c.ResponseWriter().Written() {
+			c.String(http.StatusBadRequest, err.Error())
+		}
+	})
+
+	large := `{"name":"` + strings.Repeat("a", 64) + `"}`
+	tests := []struct {
+		name    string
+		path    string
+		body    io.Reader
+		chunked bool
+		want    int
+	}{
+		{"small", "/bind", strings.NewReader(`{"name":"a"}`), false, http.StatusOK},
+		{"content length", "/bind", strings.NewReader(large), false, http.StatusRequestEntityTooLarge},
+		{"chunked bind", "/bind", strings.NewReader(large), true, http.StatusRequestEntityTooLarge},
+		{"chunked body with", "/body", strings.NewReader(large), true, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, tt.body)
+			req.Header.Set("Content-Type", "application/json")
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			seen = 0
+			s.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if seen != tt.want {
+				t.Errorf("middleware saw status %d, want %d", seen, tt.want)
+			}
+		})
+	}
+}
@@ -2,19 +2,15 @@ package slimgo
 
 import (
 	"bytes"
-	"crypto/hmac"
-	"crypto/sha1"
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"github.com/gitwillsky/slimgo/binding"
 	"io"
-	"net"
+	"io/ioutil"
 	"net/http"
-	"os"
+	"net/url"
 	"path"
 	"regexp"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -33,14 +29,45 @@ type Context interface {
 	ResponseHeader() http.Header
 	WriteResponseHeader(code int)
 	JSON(statusCode int, data interface{})
+	JSONStream(statusCode int, iter JSONIterator) error
+	IndentedJSON(statusCode int, data interface{})
+	JSONP(statusCode int, data interface{})
+	SecureJSON(statusCode int, data interface{})
+	AsciiJSON(statusCode int, data interface{})
 	String(statusCode int, data string)
+	Redirect(code int, url string) error
+	File(name string)
+	Attachment(name, filename string)
+	ServeContent(name string, modtime time.Time, content io.ReadSeeker)
+	DataFromReader(statusCode int, contentLength int64, contentType string, reader io.Reader, extraHeaders map[string]string) error
 	Bind(target interface{}) error
+	BindQuery(target interface{}) error
+	BindHeader(target interface{}) error
+	BindUri(target interface{}) error
+	ShouldBindBodyWith(target interface{}, bb binding.BindingBody) error
+	BindPatch(target interface{}) (*binding.Patch, error)
+	DecodeStream(newRecord func() interface{}, handle func(index int, record interface{}) error) error
+	Language() string
+	TranslateError(err error) error
+	Upload(folder string, opts UploadOptions) ([]*UploadedFile, error)
+	UploadTo(storage UploadStorage, opts UploadOptions) ([]*UploadedFile, error)
 	SaveUploadFiles(folder string, maxLen int, allowExt string) ([]string, error)
 	SetCookie(key string, value string, cookiePath string, maxAge int) error
-	SetSecureCookie(secret, cookieName, cookieValue, cookiePath string, cookieMaxDay int) error
+	SetHTTPCookie(cookie *http.Cookie) error
+	DeleteCookie(key string, cookiePath string) error
+	SetSecureCookie(secret, cookieName, cookieValue, cookiePath string, cookieMaxAge int) error
 	Cookie(key string) string
 	SecureCookie(secret, key string) string
+	SetEncryptedCookie(secret, cookieName, cookieValue, cookiePath string, cookieMaxAge int) error
+	EncryptedCookie(secret, key string) string
+	Session() *Session
+	Flash(kind, message string) error
+	Flashes() []Flash
+	Logger() StructuredLogger
+	AddLogFields(fields ...Field)
 	ClientIP() string
+	Scheme() string
+	Host() string
 	Next()
 	Abort()
 }
@@ -55,6 +82,11 @@ type context struct {
 	handlers []Handler
 	index    int
 	server   *Server
+	session  *Session
+	flash    *flashState
+
+	logFields []Field
+	log       StructuredLogger // cached by Logger
 }
 
 var contextPool = sync.Pool{
@@ -75,6 +107,10 @@ func (c *context) init(s *Server, res http.ResponseWriter, req *http.Request, mi
 	c.request = req
 	c.data = &sync.Map{}
 	c.handlers = append(c.handlers, middleware...)
+	// an oversized body is rejected after the middleware, so they see the 413
+	if s.maxBodySize > 0 && !limitRequestBody(req, s.maxBodySize) {
+		c.handlers = append(c.handlers, bodyTooLarge)
+	}
 
 	// find router handler
 	handlers, regPath, ps := c.server.router.GetHandlers(req)
@@ -94,6 +130,10 @@ func (c *context) recycle() {
 	c.params = nil
 	c.handlers = c.handlers[:0]
 	c.index = 0
+	c.session = nil
+	c.flash = nil
+	c.logFields = nil
+	c.log = nil
 	contextPool.Put(c)
 }
 
@@ -155,9 +195,15 @@ func (c *context) run() {
 			fileName, file, line := GetFuncInfo(handler)
 			fileName = fileName[strings.LastIndexByte(fileName, '.')+1:]
 			_, file = path.Split(file)
-			c.server.logger.Debugf("%s %s {%s}",
-				c.request.Method, c.request.URL.String(),
-				fmt.Sprintf("%s[%d]:%s", file, line, fileName))
+			if requestID := c.requestID(); requestID != "" {
+				c.server.logger.Debugf("[%s] %s %s {%s}", requestID,
+					c.request.Method, c.request.URL.String(),
+					fmt.Sprintf("%s[%d]:%s", file, line, fileName))
+			} else {
+				c.server.logger.Debugf("%s %s {%s}",
+					c.request.Method, c.request.URL.String(),
+					fmt.Sprintf("%s[%d]:%s", file, line, fileName))
+			}
 		}
 
 		handler(c)
@@ -176,14 +222,10 @@ func (c *context) Abort() {
 	c.index = len(c.handlers)
 }
 
+// JSON encodes data before the status is written, so an encoding error
+// results in a clean 500 response. The output is indented in Debug mode.
 func (c *context) JSON(statusCode int, data interface{}) {
-	c.response.Header().Set("Content-Type", "application/json;charset=utf-8")
-	c.WriteResponseHeader(statusCode)
-
-	if err := json.NewEncoder(c.response).Encode(data); err != nil {
-		http.Error(c.response, err.Error(), 500)
-		return
-	}
+	c.renderJSON(statusCode, data, jsonRender{indent: c.server.mode == Debug})
 }
 
 func (c *context) String(statusCode int, data string) {
@@ -193,150 +235,273 @@ func (c *context) String(statusCode int, data string) {
 	_, _ = fmt.Fprintln(c.response, data)
 }
 
+// Redirect redirects to url with a 3xx code. To prevent open redirects url
+// must be a relative path or an absolute URL on the host of the request.
+func (c *context) Redirect(code int, url string) error {
+	if code < http.StatusMultipleChoices || code > http.StatusPermanentRedirect {
+		return fmt.Errorf("invalid redirect code %d", code)
+	}
+	if !isSafeRedirect(url, c.Host()) {
+		return fmt.Errorf("unsafe redirect url %q", url)
+	}
+	http.Redirect(c.response, c.request, url, code)
+	return nil
+}
+
 // 文件接收
 // floder 文件所要保存的目录
 // maxLen 文件最大长度
 // 允许的扩展名[正则表达式]，例如：(.png|.jpeg|.jpg|.gif)
+//
+// Deprecated: use Upload, which checks the sniffed content type and limits
+// each file. SaveUploadFiles is Upload with the sanitized client filenames;
+// allowExt must match the client extension and the extension of the sniffed
+// content type, so "avatar.png" holding HTML is rejected.
 func (c *context) SaveUploadFiles(folder string, maxLen int, allowExt string) ([]string, error) {
-	result := make([]string, 0)
 	// 允许的扩展名正则匹配
 	regExt, err := regexp.Compile(allowExt)
 	if err != nil {
 		return nil, err
 	}
 
-	// 文件长度限制
-	length, err := strconv.Atoi(c.request.Header.Get("Content-Length"))
-	if err != nil || (length > maxLen) {
-		return nil, errors.New("Upload file is too big!")
+	files, err := c.Upload(folder, UploadOptions{
+		MaxTotalSize: int64(maxLen),
+		KeepFilename: true,
+		accept: func(filename string) bool {
+			return regExt.MatchString(strings.ToLower(path.Ext(filename)))
+		},
+		acceptExt: regExt.MatchString,
+	})
+	if err == ErrNoUpload {
+		return nil, errors.New("Invalid upload file!")
 	}
-
-	// get the multipart reader for the request.
-	reader, err := c.request.MultipartReader()
 	if err != nil {
 		return nil, err
 	}
 
-	// 文件全路径
-	var fullFilePath string
-	// 读取每个文件,保存。
-	for {
-		// 通过boundary，获取每个文件数据。
-		part, err := reader.NextPart()
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return nil, err
+	result := make([]string, 0, len(files))
+	for _, f := range files {
+		result = append(result, f.Name)
+	}
+	return result, nil
+}
+
+// BindJSON parse json request
+func (c *context) Bind(target interface{}) error {
+	b := binding.Default(c.request.Method, filterFlags(c.request.Header.Get("Content-Type")))
+	if b.Name() == "json" {
+		if opts, ok := c.jsonOptions(); ok {
+			b = binding.NewJSON(opts)
 		}
+	}
+	return c.checkBodySize(b.Bind(c.request, target))
+}
 
-		// 检查文件名
-		fileName := part.FileName()
-		if fileName == "" {
-			continue
+// jsonOptions returns the JSON decoder options of the route or the server.
+func (c *context) jsonOptions() (binding.JSONOptions, bool) {
+	if v, ok := c.Data(JSONOptionsKey); ok {
+		if opts, ok := v.(binding.JSONOptions); ok {
+			return opts, true
 		}
-		// 检查文件类型
-		fileExt := strings.ToLower(path.Ext(fileName))
-		if !regExt.MatchString(fileExt) {
-			continue
-			//return nil, errors.New("Invalid upload file type!")
+	}
+	if c.server.jsonOptions != nil {
+		return *c.server.jsonOptions, true
+	}
+	return binding.JSONOptions{}, false
+}
+
+// BindQuery binds the url query with "form" tags whatever the method is.
+func (c *context) BindQuery(target interface{}) error {
+	return binding.Query.Bind(c.request, target)
+}
+
+// BindHeader binds the request headers with "header" tags.
+func (c *context) BindHeader(target interface{}) error {
+	return binding.Header.Bind(c.request, target)
+}
+
+// BindUri binds the router params with "uri" tags.
+func (c *context) BindUri(target interface{}) error {
+	ps := make(map[string][]string)
+	if c.params != nil {
+		for _, v := range *c.params {
+			ps[v.key] = []string{v.value}
+		}
+	}
+	return binding.Uri.BindUri(ps, target)
+}
+
+// ShouldBindBodyWith binds the request body with bb and keeps the body bytes
+// in Data under BodyBytesKey, so it can be bound several times, e.g. first
+// to verify a signature and then into the payload. binding.JSON decodes with
+// the JSON options of the route or the server.
+func (c *context) ShouldBindBodyWith(target interface{}, bb binding.BindingBody) error {
+	var body []byte
+	if cb, ok := c.Data(BodyBytesKey); ok {
+		body, _ = cb.([]byte)
+	}
+	if body == nil {
+		if c.request.Body == nil {
+			return errors.New("invalid request")
 		}
-		// 构造新的文件名和全路径
-		subFilePath := fmt.Sprintf("%s/%s", time.Now().Format("2006-01-02"), fileName)
-		fullFilePath = path.Clean(folder + subFilePath)
-
-		// 新建文件夹,0777
-		_ = os.MkdirAll(path.Dir(fullFilePath), os.ModePerm)
-		// 建立目标文件
-		dst, err := os.Create(fullFilePath)
+		b, err := ioutil.ReadAll(c.request.Body)
 		if err != nil {
-			return nil, err
+			return c.checkBodySize(err)
 		}
-		defer dst.Close()
-
-		// 拷贝数据流到文件
-		if _, err = io.Copy(dst, part); err != nil {
-			return nil, err
+		_ = c.request.Body.Close()
+		body = b
+		c.request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		c.PutData(BodyBytesKey, body)
+	}
+	if bb == binding.JSON {
+		if opts, ok := c.jsonOptions(); ok {
+			bb = binding.NewJSON(opts)
 		}
-
-		// 将成功写入的文件路径信息写入结果集
-		result = append(result, subFilePath)
 	}
+	return bb.BindBody(body, target)
+}
 
-	// 检查结果
-	if len(result) == 0 {
-		return nil, errors.New("Invalid upload file!")
+// BindPatch applies the request body to target as a JSON Patch when the
+// content type is application/json-patch+json, and as a JSON Merge Patch
+// otherwise, with the JSON options of the route or the server. The returned
+// Patch tells which fields were set or set to null.
+func (c *context) BindPatch(target interface{}) (*binding.Patch, error) {
+	if c.request.Body == nil {
+		return nil, errors.New("invalid request")
 	}
+	defer c.request.Body.Close()
 
-	return result, nil
-}
-
-// BindJSON parse json request
-func (c *context) Bind(target interface{}) error {
-	b := binding.Default(c.request.Method, filterFlags(c.request.Header.Get("Content-Type")))
-	return b.Bind(c.request, target)
+	body, err := ioutil.ReadAll(c.request.Body)
+	if err != nil {
+		return nil, c.checkBodySize(err)
+	}
+	opts, _ := c.jsonOptions()
+	if filterFlags(c.request.Header.Get("Content-Type")) == binding.MIMEJSONPatch {
+		return binding.ApplyJSONPatchWithOptions(body, target, opts)
+	}
+	return binding.ApplyMergePatchWithOptions(body, target, opts)
 }
 
-// ClientIP() return client IP.
-// if in proxy, return first proxy id;
-// if error ,return 127.0.0.1;
-func (c *context) ClientIP() string {
-	clientIP := strings.TrimSpace(c.request.Header.Get("X-Real-Ip"))
-	if len(clientIP) > 0 {
-		return clientIP
+// DecodeStream decodes the request body record by record, as newline-delimited
+// JSON when the content type is application/x-ndjson and as a JSON array otherwise.
+// newRecord returns a pointer to decode each record into, handle is called
+// with every valid record.
+// Invalid records are skipped and returned as binding.RecordErrors at the end,
+// decoding stops after binding.MaxRecordErrors of them. An error from handle
+// stops the stream and is returned as a *binding.RecordError.
+func (c *context) DecodeStream(newRecord func() interface{}, handle func(index int, record interface{}) error) error {
+	if c.request.Body == nil {
+		return errors.New("invalid request")
 	}
-	clientIP = c.request.Header.Get("X-Forwarded-For")
-	if index := strings.IndexByte(clientIP, ','); index >= 0 {
-		clientIP = clientIP[0:index]
+	defer c.request.Body.Close()
+
+	opts, _ := c.jsonOptions()
+	ndjson := filterFlags(c.request.Header.Get("Content-Type")) == binding.MIMENDJSON
+	dec := binding.NewStreamDecoder(c.request.Body, ndjson, opts)
+
+	var recordErrs binding.RecordErrors
+	for {
+		record := newRecord()
+		err := dec.Next(record)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if re, ok := err.(*binding.RecordError); ok {
+				if recordErrs = append(recordErrs, re); len(recordErrs) >= binding.MaxRecordErrors {
+					return recordErrs
+				}
+				continue
+			}
+			return c.checkBodySize(err)
+		}
+		if err := handle(dec.Index(), record); err != nil {
+			return &binding.RecordError{Index: dec.Index(), Err: err}
+		}
 	}
-	clientIP = strings.TrimSpace(clientIP)
-	if len(clientIP) > 0 {
-		return clientIP
+
+	if len(recordErrs) > 0 {
+		return recordErrs
 	}
-	if ip, _, err := net.SplitHostPort(strings.TrimSpace(c.request.RemoteAddr)); err == nil {
-		return ip
+	return nil
+}
+
+// Language returns the best language with a message catalog for the
+// request's Accept-Language header.
+func (c *context) Language() string {
+	return binding.MatchLanguage(c.request.Header.Get("Accept-Language"))
+}
+
+// TranslateError localizes binding and validation errors for the request's language.
+// Other errors are returned unchanged.
+func (c *context) TranslateError(err error) error {
+	if err == nil {
+		return nil
 	}
-	return "127.0.0.1"
+	return binding.Translate(err, c.Language())
 }
 
-// Set cookie.
+// Set cookie. A negative maxAge makes a session cookie, 0 deletes it.
+// Domain, Secure, HttpOnly and SameSite come from Server.SetCookieDefaults.
 func (c *context) SetCookie(key string, value string, cookiePath string, maxAge int) error {
-	var b bytes.Buffer
-
-	_, _ = fmt.Fprintf(&b, "%s=%s", strings.TrimSpace(key), strings.TrimSpace(value))
+	cookie := &http.Cookie{
+		Name:  strings.TrimSpace(key),
+		Value: strings.TrimSpace(value),
+		Path:  strings.TrimSpace(cookiePath),
+	}
 
-	// set max age.
-	if maxAge >= 0 {
-		_, _ = fmt.Fprintf(&b, "; Max-Age=%d", maxAge)
+	switch {
+	case maxAge == 0:
+		cookie.MaxAge = -1 // Max-Age=0
+	case maxAge > 0:
+		cookie.MaxAge = maxAge
 	}
-	// set path.
-	_, _ = fmt.Fprintf(&b, "; Path=%s", strings.TrimSpace(cookiePath))
 
-	// output to header.
-	c.response.Header().Add("Set-Cookie", b.String())
+	return c.SetHTTPCookie(cookie)
+}
+
+// SetHTTPCookie adds a Set-Cookie header for cookie. Attributes which are
+// not set on cookie are taken from Server.SetCookieDefaults. The value is
+// escaped with url.QueryEscape, so any string survives, and Cookie unescapes it.
+func (c *context) SetHTTPCookie(cookie *http.Cookie) error {
+	cookie = c.server.cookieDefaults.apply(cookie)
+	cookie.Value = url.QueryEscape(cookie.Value)
 
+	v := cookie.String()
+	if v == "" {
+		return fmt.Errorf("invalid cookie name %q", cookie.Name)
+	}
+	c.response.Header().Add("Set-Cookie", v)
 	return nil
 }
 
-// Set secure cookie.
-func (c *context) SetSecureCookie(secret, cookieName, cookieValue, cookiePath string, cookieMaxDay int) error {
-	// encoding value to string.
-	s := base64.URLEncoding.EncodeToString([]byte(cookieValue))
-	// get time stamp.
-	timestamp := strconv.FormatInt(time.Now().UnixNano(), 10)
-	// new hmac to crypto secret.
-	h := hmac.New(sha1.New, []byte(secret))
-	_, _ = fmt.Fprintf(h, "%s%s", s, timestamp)
-	sig := fmt.Sprintf("%02x", h.Sum(nil))
-	// v|timestamp|sig
-	cookie := strings.Join([]string{s, timestamp, sig}, "|")
+// DeleteCookie tells the client to remove the cookie key set with cookiePath.
+func (c *context) DeleteCookie(key string, cookiePath string) error {
+	return c.SetHTTPCookie(&http.Cookie{
+		Name:    key,
+		Path:    cookiePath,
+		MaxAge:  -1,
+		Expires: time.Unix(1, 0),
+	})
+}
 
-	_ = c.SetCookie(cookieName, cookie, cookiePath, cookieMaxDay)
+// Set secure cookie. The value is signed with HMAC-SHA256 and expires with
+// cookieMaxAge (in seconds), or after Server.SetSessionCookieTTL if it is
+// negative. An empty secret signs with the newest secret set by
+// Server.SetCookieSecrets.
+func (c *context) SetSecureCookie(secret, cookieName, cookieValue, cookiePath string, cookieMaxAge int) error {
+	secrets := c.cookieSecrets(secret)
+	if len(secrets) == 0 || secrets[0] == "" {
+		return errors.New("secure cookie needs a secret")
+	}
 
-	return nil
+	cookie := signCookie(secrets[0], cookieName, cookieValue, c.cookieExpires(cookieMaxAge))
+	return c.SetCookie(cookieName, cookie, cookiePath, cookieMaxAge)
 }
 
-// Get secure cookie.
+// Get secure cookie. It returns "" when the cookie is missing, expired or
+// not signed by secret, or by one of the secrets set by
+// Server.SetCookieSecrets when secret is empty.
 func (c *context) SecureCookie(secret, key string) string {
 	cookie := c.Cookie(key)
 
@@ -344,34 +509,50 @@ func (c *context) SecureCookie(secret, key string) string {
 		return ""
 	}
 
-	parts := strings.SplitN(cookie, "|", 3)
+	value, _ := verifyCookie(c.cookieSecrets(secret), key, cookie)
+	return value
+}
 
-	if len(parts) != 3 {
-		return ""
+// Set encrypted cookie. Unlike SetSecureCookie the value is encrypted with
+// AES-GCM, so the client can not read it. The secrets work like the ones of
+// SetSecureCookie.
+func (c *context) SetEncryptedCookie(secret, cookieName, cookieValue, cookiePath string, cookieMaxAge int) error {
+	secrets := c.cookieSecrets(secret)
+	if len(secrets) == 0 || secrets[0] == "" {
+		return errors.New("encrypted cookie needs a secret")
 	}
 
-	s := parts[0]
-	timestamp := parts[1]
-	sig := parts[2]
+	cookie, err := encryptCookie(secrets[0], cookieName, cookieValue, c.cookieExpires(cookieMaxAge))
+	if err != nil {
+		return err
+	}
+	return c.SetCookie(cookieName, cookie, cookiePath, cookieMaxAge)
+}
 
-	// judge secret correct.
-	h := hmac.New(sha1.New, []byte(secret))
-	_, _ = fmt.Fprintf(h, "%s%s", s, timestamp)
+// Get encrypted cookie. It returns "" when the cookie is missing, expired or
+// can not be decrypted with secret, or with one of the server secrets when
+// secret is empty.
+func (c *context) EncryptedCookie(secret, key string) string {
+	cookie := c.Cookie(key)
 
-	if fmt.Sprintf("%02x", h.Sum(nil)) != sig {
+	if cookie == "" {
 		return ""
 	}
 
-	res, _ := base64.URLEncoding.DecodeString(s)
-	return string(res)
+	value, _ := decryptCookie(c.cookieSecrets(secret), key, cookie)
+	return value
 }
 
-// Get cookie
+// Get cookie, unescaped as written by SetCookie.
 func (c *context) Cookie(key string) string {
 	cookie, err := c.request.Cookie(key)
 	if err != nil {
 		return ""
 	}
 
-	return strings.TrimSpace(cookie.Value)
+	value := strings.TrimSpace(cookie.Value)
+	if v, err := url.QueryUnescape(value); err == nil {
+		value = v
+	}
+	return value
 }
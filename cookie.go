@@ -0,0 +1,199 @@
+package slimgo
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CookieOptions are the cookie attributes applied by Server.SetCookieDefaults.
+type CookieOptions struct {
+	Path     string
+	Domain   string
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+}
+
+// apply returns a copy of cookie with the unset attributes taken from o.
+// Secure and HttpOnly can only be turned on by the defaults.
+func (o CookieOptions) apply(cookie *http.Cookie) *http.Cookie {
+	result := *cookie
+	if result.Path == "" {
+		result.Path = o.Path
+	}
+	if result.Domain == "" {
+		result.Domain = o.Domain
+	}
+	if result.SameSite == 0 {
+		result.SameSite = o.SameSite
+	}
+	result.Secure = result.Secure || o.Secure
+	result.HttpOnly = result.HttpOnly || o.HttpOnly
+	return &result
+}
+
+// SetCookieDefaults sets the attributes of all cookies written by the
+// Context cookie methods, e.g. Secure and HttpOnly in Release mode.
+func (s *Server) SetCookieDefaults(opts CookieOptions) {
+	s.cookieDefaults = opts
+}
+
+// SetCookieSecrets sets the secrets of signed and encrypted cookies, newest first.
+// The newest secret signs, all of them verify, so old secrets can be kept
+// until the cookies signed with them have expired.
+func (s *Server) SetCookieSecrets(secrets ...string) {
+	s.cookieSecrets = secrets
+}
+
+// defaultSessionCookieTTL is how long signed and encrypted cookies without
+// a max age are accepted.
+const defaultSessionCookieTTL = 24 * time.Hour
+
+// SetSessionCookieTTL sets how long signed and encrypted cookies with a
+// negative max age are accepted. The browser keeps them until it is closed,
+// which may be never, so the server has to end them, 24 hours by default.
+func (s *Server) SetSessionCookieTTL(ttl time.Duration) {
+	s.sessionCookieTTL = ttl
+}
+
+// cookieSecrets returns the secrets to sign and verify with: only secret if
+// it is given, so cookies of one purpose are never accepted for another,
+// the server secrets otherwise.
+func (c *context) cookieSecrets(secret string) []string {
+	if secret == "" {
+		return c.server.cookieSecrets
+	}
+	return []string{secret}
+}
+
+// cookieExpires returns the unix time a cookie with maxAge expires at.
+// Session cookies expire after the server's session cookie TTL.
+func (c *context) cookieExpires(maxAge int) int64 {
+	if maxAge < 0 {
+		return time.Now().Add(c.server.sessionCookieTTL).Unix()
+	}
+	return time.Now().Unix() + int64(maxAge)
+}
+
+// signCookie returns "value|expires|signature" with the value base64 encoded
+// and an HMAC-SHA256 signature over name, value and expires.
+func signCookie(secret, name, value string, expires int64) string {
+	v := base64.URLEncoding.EncodeToString([]byte(value))
+	e := strconv.FormatInt(expires, 10)
+	return strings.Join([]string{v, e, cookieSignature(secret, name, v, e)}, "|")
+}
+
+// verifyCookie returns the value of a signed cookie if any of secrets signed
+// it and it has not expired.
+func verifyCookie(secrets []string, name, cookie string) (string, bool) {
+	parts := strings.SplitN(cookie, "|", 3)
+	if len(parts) != 3 {
+		return "", false
+	}
+	v, e, sig := parts[0], parts[1], parts[2]
+
+	expires, err := strconv.ParseInt(e, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return "", false
+	}
+
+	var valid bool
+	for _, secret := range secrets {
+		if secret != "" && hmac.Equal([]byte(sig), []byte(cookieSignature(secret, name, v, e))) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return "", false
+	}
+
+	value, err := base64.URLEncoding.DecodeString(v)
+	if err != nil {
+		return "", false
+	}
+	return string(value), true
+}
+
+func cookieSignature(secret, name, value, expires string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	_, _ = h.Write([]byte(name + "|" + value + "|" + expires))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// encryptionKey derives the AES-256 key of encrypted cookies from a secret,
+// so signing and encryption never use the same key.
+func encryptionKey(secret string) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	_, _ = h.Write([]byte("slimgo encrypted cookie"))
+	return h.Sum(nil)
+}
+
+func newCookieAEAD(secret string) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(encryptionKey(secret))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptCookie seals expires and value with AES-GCM, authenticating the
+// cookie name too, and returns base64(nonce|ciphertext).
+func encryptCookie(secret, name, value string, expires int64) (string, error) {
+	aead, err := newCookieAEAD(secret)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+8+len(value)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	plain := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(plain, uint64(expires))
+	copy(plain[8:], value)
+
+	sealed := aead.Seal(nonce, nonce, plain, []byte(name))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// decryptCookie returns the value of an encrypted cookie if any of secrets
+// opens it and it has not expired.
+func decryptCookie(secrets []string, name, cookie string) (string, bool) {
+	sealed, err := base64.RawURLEncoding.DecodeString(cookie)
+	if err != nil {
+		return "", false
+	}
+
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		aead, err := newCookieAEAD(secret)
+		if err != nil || len(sealed) < aead.NonceSize() {
+			return "", false
+		}
+		plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(name))
+		if err != nil || len(plain) < 8 {
+			continue
+		}
+		expires := int64(binary.BigEndian.Uint64(plain))
+		if time.Now().Unix() > expires {
+			return "", false
+		}
+		return string(plain[8:]), true
+	}
+	return "", false
+}
@@ -0,0 +1,152 @@
+package slimgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyCookie(t *testing.T) {
+	future := time.Now().Add(time.Hour).Unix()
+	signed := signCookie("new", "sid", "user=1", future)
+	parts := strings.Split(signed, "|")
+
+	tests := []struct {
+		name    string
+		secrets []string
+		cookie  string
+		want    string
+		ok      bool
+	}{
+		{"valid", []string{"new"}, signed, "user=1", true},
+		{"rotated secret", []string{"newer", "new"}, signed, "user=1", true},
+		{"unknown secret", []string{"other"}, signed, "", false},
+		{"empty secret", []string{""}, signed, "", false},
+		{"expired", []string{"new"}, signCookie("new", "sid", "user=1", time.Now().Add(-time.Second).Unix()), "", false},
+		{"no expiry", []string{"new"}, signCookie("new", "sid", "user=1", 0), "", false},
+		{"tampered value", []string{"new"}, "dXNlcj0y|" + parts[1] + "|" + parts[2], "", false},
+		{"extended expiry", []string{"new"}, parts[0] + "|" + "99999999999|" + parts[2], "", false},
+		{"tampered signature", []string{"new"}, parts[0] + "|" + parts[1] + "|" + strings.Repeat("0", 64), "", false},
+		{"other cookie name", []string{"new"}, signCookie("new", "admin", "user=1", future), "", false},
+		{"malformed", []string{"new"}, "user=1", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := verifyCookie(tt.secrets, "sid", tt.cookie)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("verifyCookie = %q, %v; want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestDecryptCookie(t *testing.T) {
+	future := time.Now().Add(time.Hour).Unix()
+	sealed, err := encryptCookie("new", "sid", "user=1", future)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, _ := encryptCookie("new", "sid", "user=1", time.Now().Add(-time.Second).Unix())
+	flipped := []byte(sealed)
+	flipped[len(flipped)-2] ^= 1
+
+	tests := []struct {
+		name    string
+		secrets []string
+		cookie  string
+		ok      bool
+	}{
+		{"valid", []string{"new"}, sealed, true},
+		{"rotated secret", []string{"newer", "new"}, sealed, true},
+		{"unknown secret", []string{"other"}, sealed, false},
+		{"expired", []string{"new"}, expired, false},
+		{"tampered", []string{"new"}, string(flipped), false},
+		{"truncated", []string{"new"}, sealed[:10], false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := decryptCookie(tt.secrets, "sid", tt.cookie)
+			if ok != tt.ok || (ok && got != "user=1") {
+				t.Errorf("decryptCookie = %q, %v; want ok %v", got, ok, tt.ok)
+			}
+		})
+	}
+	if _, ok := decryptCookie([]string{"new"}, "admin", sealed); ok {
+		t.Error("decryptCookie accepted the cookie under another name")
+	}
+}
+
+func TestSecureCookieSecrets(t *testing.T) {
+	s := New()
+	s.SetMode(Release)
+	s.SetCookieSecrets("server")
+	s.GET("/set", func(c Context) {
+		_ = c.SetSecureCookie(c.Request().URL.Query().Get("secret"), "v", "1", "/", -1)
+	})
+	var got string
+	s.GET("/get", func(c Context) {
+		got = c.SecureCookie(c.Request().URL.Query().Get("secret"), "v")
+	})
+
+	tests := []struct {
+		name      string
+		setSecret string
+		getSecret string
+		want      string
+	}{
+		{"server secret", "", "", "1"},
+		{"own secret", "purpose", "purpose", "1"},
+		{"server cookie for own secret", "", "purpose", ""},
+		{"own cookie for server secrets", "purpose", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/set?secret="+tt.setSecret, nil))
+			req := httptest.NewRequest(http.MethodGet, "/get?secret="+tt.getSecret, nil)
+			for _, c := range w.Result().Cookies() {
+				req.AddCookie(c)
+			}
+			got = "unset"
+			s.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("SecureCookie = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCookieEscaping(t *testing.T) {
+	s := New()
+	s.SetMode(Release)
+	values := []string{"张三", "a b;c=d", `"quoted"`, "100%", "a+b"}
+	s.GET("/set", func(c Context) {
+		for i, v := range values {
+			if err := c.SetCookie("c"+string(rune('0'+i)), v, "/", 100); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+	got := map[string]string{}
+	s.GET("/get", func(c Context) {
+		for i := range values {
+			name := "c" + string(rune('0'+i))
+			got[name] = c.Cookie(name)
+		}
+	})
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/set", nil))
+	req := httptest.NewRequest(http.MethodGet, "/get", nil)
+	for _, c := range w.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	for i, v := range values {
+		if name := "c" + string(rune('0'+i)); got[name] != v {
+			t.Errorf("cookie %s = %q, want %q", name, got[name], v)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package slimgo
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/gitwillsky/slimgo/binding"
+)
+
+// CSRFTokenKey is the Data key of the CSRF token of the request.
+const CSRFTokenKey = "csrf_token"
+
+// csrfFieldKey is the Data key of the form field name used by CSRFField.
+const csrfFieldKey = "_slimgo/csrffield"
+
+// csrfSessionKey is the session key of the token when sessions are enabled.
+const csrfSessionKey = "_slimgo/csrf"
+
+const csrfTokenLength = 32
+
+// CSRFOptions configures the CSRF middleware.
+type CSRFOptions struct {
+	// HeaderName is the request header carrying the token, "X-CSRF-Token" by default.
+	HeaderName string
+	// FieldName is the form field carrying the token, "csrf_token" by default.
+	// In multipart forms it must come before the files.
+	FieldName string
+	// CookieName is the cookie of the double-submit token, "slimgo_csrf" by
+	// default. It is not used when sessions are enabled.
+	CookieName string
+	// CookiePath is the path of the cookie, "/" by default.
+	CookiePath string
+	// Exempt lists route patterns which are not checked, e.g. "/webhook/:id".
+	// A pattern ending with "*" matches all routes with that prefix.
+	Exempt []string
+	// Skip reports whether the request is not checked.
+	Skip func(c Context) bool
+	// ErrorHandler writes the response of a rejected request, 403 by default.
+	ErrorHandler Handler
+}
+
+// CSRF returns a middleware which protects unsafe methods against cross-site
+// request forgery. The token is kept in the session when Server.UseSessions
+// was called, otherwise in a cookie which must be sent back along with the
+// token (double submit). Forms include it with CSRFField, scripts send
+// Data(CSRFTokenKey) in the header.
+func CSRF(opts CSRFOptions) Handler {
+	if opts.HeaderName == "" {
+		opts.HeaderName = "X-CSRF-Token"
+	}
+	if opts.FieldName == "" {
+		opts.FieldName = "csrf_token"
+	}
+	if opts.CookieName == "" {
+		opts.CookieName = "slimgo_csrf"
+	}
+	if opts.CookiePath == "" {
+		opts.CookiePath = "/"
+	}
+	if opts.ErrorHandler == nil {
+		opts.ErrorHandler = func(c Context) {
+			c.String(http.StatusForbidden, "invalid CSRF token")
+		}
+	}
+
+	return func(c Context) {
+		secret := csrfSecret(c, &opts)
+		c.PutData(CSRFTokenKey, maskCSRFToken(secret))
+		c.PutData(csrfFieldKey, opts.FieldName)
+
+		if isSafeMethod(c.Request().Method) || csrfExempt(c.RegRelativePath(), opts.Exempt) {
+			return
+		}
+		if opts.Skip != nil && opts.Skip(c) {
+			return
+		}
+
+		sent := c.Request().Header.Get(opts.HeaderName)
+		if sent == "" {
+			sent = csrfFormToken(c.Request(), opts.FieldName)
+		}
+		if token := unmaskCSRFToken(sent); token == nil || subtle.ConstantTimeCompare(token, secret) != 1 {
+			opts.ErrorHandler(c)
+			c.Abort()
+		}
+	}
+}
+
+// CSRFToken returns the CSRF token of the request, "" without the CSRF middleware.
+func CSRFToken(c Context) string {
+	token, _ := c.Data(CSRFTokenKey)
+	s, _ := token.(string)
+	return s
+}
+
+// CSRFField returns a hidden form field with the CSRF token, for templates.
+func CSRFField(c Context) template.HTML {
+	token := CSRFToken(c)
+	if token == "" {
+		return ""
+	}
+	field, _ := c.Data(csrfFieldKey)
+	name, _ := field.(string)
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(name) +
+		`" value="` + template.HTMLEscapeString(token) + `">`)
+}
+
+// maxCSRFScan limits the bytes of a multipart body read to find the token.
+const maxCSRFScan = 64 << 10
+
+// csrfFormToken returns the token form field. Multipart bodies are not parsed
+// as a whole, which would keep Context.Upload from streaming the files: the
+// token is read from the fields before the first file, so it must come first
+// in upload forms, and the bytes read are put back.
+func csrfFormToken(req *http.Request, field string) string {
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != binding.MIMEMultipartPOSTForm || req.MultipartForm != nil || req.Body == nil {
+		return req.PostFormValue(field)
+	}
+
+	var read bytes.Buffer
+	defer func() { replayBody(req, read.Bytes()) }()
+
+	mr := multipart.NewReader(io.TeeReader(io.LimitReader(req.Body, maxCSRFScan), &read), params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err != nil || part.FileName() != "" {
+			return ""
+		}
+		if part.FormName() == field {
+			b, _ := ioutil.ReadAll(io.LimitReader(part, 4*csrfTokenLength))
+			return string(b)
+		}
+	}
+}
+
+// csrfSecret returns the token of the client, a new one when it has none.
+func csrfSecret(c Context, opts *CSRFOptions) []byte {
+	if ctx, ok := c.(*context); ok && ctx.server.sessionStore != nil {
+		s := ctx.Session()
+		if v, ok := s.Get(csrfSessionKey).([]byte); ok && len(v) == csrfTokenLength {
+			return v
+		}
+		secret := newCSRFSecret()
+		s.Set(csrfSessionKey, secret)
+		return secret
+	}
+
+	if v, err := base64.RawURLEncoding.DecodeString(c.Cookie(opts.CookieName)); err == nil && len(v) == csrfTokenLength {
+		return v
+	}
+	secret := newCSRFSecret()
+	_ = c.SetHTTPCookie(&http.Cookie{
+		Name:     opts.CookieName,
+		Value:    base64.RawURLEncoding.EncodeToString(secret),
+		Path:     opts.CookiePath,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return secret
+}
+
+func newCSRFSecret() []byte {
+	b := make([]byte, csrfTokenLength)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// maskCSRFToken XORs the secret with a random pad, so the token in the page
+// differs on every response and can not be recovered by BREACH attacks.
+func maskCSRFToken(secret []byte) string {
+	pad := newCSRFSecret()
+	token := make([]byte, 2*csrfTokenLength)
+	copy(token, pad)
+	for i := range secret {
+		token[csrfTokenLength+i] = pad[i] ^ secret[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+func unmaskCSRFToken(token string) []byte {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(b) != 2*csrfTokenLength {
+		return nil
+	}
+	secret := make([]byte, csrfTokenLength)
+	for i := range secret {
+		secret[i] = b[i] ^ b[csrfTokenLength+i]
+	}
+	return secret
+}
+
+// isSafeMethod reports whether method does not change state by RFC 7231.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func csrfExempt(regPath string, exempt []string) bool {
+	for _, pattern := range exempt {
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(regPath, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		} else if pattern == regPath {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package slimgo
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// csrfClient is a browser of the CSRF test server: a token and its cookies.
+type csrfClient struct {
+	token   string
+	cookies []*http.Cookie
+}
+
+func newCSRFServer(sessions bool) *Server {
+	s := New()
+	s.SetMode(Release)
+	if sessions {
+		s.UseSessions(NewMemoryStore())
+	}
+	s.Use(CSRF(CSRFOptions{Exempt: []string{"/hook/*"}}))
+	s.GET("/form", func(c Context) {
+		c.String(http.StatusOK, CSRFToken(c))
+	})
+	s.POST("/submit", func(c Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	s.POST("/hook/:id", func(c Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	return s
+}
+
+func csrfVisit(t *testing.T, s *Server) csrfClient {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/form", nil))
+	token := strings.TrimSpace(w.Body.String())
+	if w.Code != http.StatusOK || token == "" {
+		t.Fatalf("GET /form = %d %q", w.Code, token)
+	}
+	return csrfClient{token: token, cookies: w.Result().Cookies()}
+}
+
+func TestCSRF(t *testing.T) {
+	for _, mode := range []struct {
+		name     string
+		sessions bool
+	}{{"double submit", false}, {"session", true}} {
+		t.Run(mode.name, func(t *testing.T) {
+			s := newCSRFServer(mode.sessions)
+			client := csrfVisit(t, s)
+			other := csrfVisit(t, s)
+
+			tests := []struct {
+				name    string
+				path    string
+				cookies []*http.Cookie
+				header  string
+				field   string
+				want    int
+			}{
+				{"valid header", "/submit", client.cookies, client.token, "", http.StatusOK},
+				{"valid field", "/submit", client.cookies, "", client.token, http.StatusOK},
+				{"second token of same client", "/submit", client.cookies, csrfVisitWith(t, s, client), "", http.StatusOK},
+				{"missing token", "/submit", client.cookies, "", "", http.StatusForbidden},
+				{"missing cookie", "/submit", nil, client.token, "", http.StatusForbidden},
+				{"token of other client", "/submit", client.cookies, other.token, "", http.StatusForbidden},
+				{"cookie of other client", "/submit", other.cookies, client.token, "", http.StatusForbidden},
+				{"garbage token", "/submit", client.cookies, "not-a-token", "", http.StatusForbidden},
+				{"truncated token", "/submit", client.cookies, client.token[:len(client.token)-2], "", http.StatusForbidden},
+				{"exempt route", "/hook/1", nil, "", "", http.StatusOK},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					form := url.Values{}
+					if tt.field != "" {
+						form.Set("csrf_token", tt.field)
+					}
+					req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(form.Encode()))
+					req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+					if tt.header != "" {
+						req.Header.Set("X-CSRF-Token", tt.header)
+					}
+					for _, ck := range tt.cookies {
+						req.AddCookie(ck)
+					}
+					w := httptest.NewRecorder()
+					s.ServeHTTP(w, req)
+					if w.Code != tt.want {
+						t.Errorf("got %d %q, want %d", w.Code, w.Body.String(), tt.want)
+					}
+				})
+			}
+		})
+	}
+}
+
+// csrfVisitWith loads the form again with the cookies of client and returns
+// the new token, which is masked differently but carries the same secret.
+func csrfVisitWith(t *testing.T, s *Server, client csrfClient) string {
+	req := httptest.NewRequest(http.MethodGet, "/form", nil)
+	for _, ck := range client.cookies {
+		req.AddCookie(ck)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	token := strings.TrimSpace(w.Body.String())
+	if token == client.token {
+		t.Fatal("token is not masked per response")
+	}
+	return token
+}
+
+func TestCSRFMultipartUpload(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...)
+
+	for _, limit := range []int64{0, 1 << 20} {
+		s := newCSRFServer(false)
+		s.SetMaxBodySize(limit)
+		s.POST("/upload", func(c Context) {
+			files, err := c.UploadTo(NewMemoryStorage(), UploadOptions{})
+			if err != nil {
+				c.String(http.StatusBadRequest, err.Error())
+				return
+			}
+			c.String(http.StatusOK, fmt.Sprintf("%d %s", len(files), c.Request().PostForm.Get("title")))
+		})
+		client := csrfVisit(t, s)
+
+		tests := []struct {
+			name       string
+			tokenFirst bool
+			token      string
+			want       int
+			body       string
+		}{
+			{"token before file", true, client.token, http.StatusOK, "1 t"},
+			{"token after file", false, client.token, http.StatusForbidden, ""},
+			{"wrong token", true, csrfVisit(t, s).token, http.StatusForbidden, ""},
+			{"no token", true, "", http.StatusForbidden, ""},
+		}
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s limit %d", tt.name, limit), func(t *testing.T) {
+				var body bytes.Buffer
+				mw := multipart.NewWriter(&body)
+				if tt.tokenFirst && tt.token != "" {
+					_ = mw.WriteField("csrf_token", tt.token)
+				}
+				_ = mw.WriteField("title", "t")
+				fw, _ := mw.CreateFormFile("file", "a.png")
+				_, _ = fw.Write(png)
+				if !tt.tokenFirst {
+					_ = mw.WriteField("csrf_token", tt.token)
+				}
+				_ = mw.Close()
+
+				req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+				req.Header.Set("Content-Type", mw.FormDataContentType())
+				for _, ck := range client.cookies {
+					req.AddCookie(ck)
+				}
+				w := httptest.NewRecorder()
+				s.ServeHTTP(w, req)
+				if w.Code != tt.want {
+					t.Fatalf("got %d %q, want %d", w.Code, w.Body.String(), tt.want)
+				}
+				if tt.body != "" && strings.TrimSpace(w.Body.String()) != tt.body {
+					t.Errorf("got %q, want %q", w.Body.String(), tt.body)
+				}
+			})
+		}
+	}
+}
@@ -2,6 +2,7 @@ package slimgo
 
 import (
 	"fmt"
+	"html"
 	"net/http"
 	"strings"
 
@@ -48,6 +49,7 @@ const tpl = `
            <p>HTTP Error %d - %s</p>
            <p>Request Method: %s</p>
            <p>Request URL: %s</p>
+           <p>Request ID: %s</p>
        </section>
        <section>
             <h3>What can I do?</h3>
@@ -69,14 +71,17 @@ const tpl = `
 `
 
 // defaultPanicHandler default panic handler
-func defaultPanicHandler(w http.ResponseWriter, req *http.Request, i interface{}, stack []byte) {
+func defaultPanicHandler(w http.ResponseWriter, req *http.Request, i interface{}, stack []byte, requestID string) {
 	contentType := req.Header.Get("Content-Type")
 	if strings.Contains(contentType, "application/json") {
 		http.Error(w, "Interval Server Error", 500)
 		return
 	}
 
-	returnHTML := fmt.Sprintf(tpl, Version, 500, http.StatusText(500), req.Method, req.URL.Path, i, stack)
+	if requestID == "" {
+		requestID = "-"
+	}
+	returnHTML := fmt.Sprintf(tpl, Version, 500, http.StatusText(500), req.Method, req.URL.Path, html.EscapeString(requestID), i, stack)
 	w.WriteHeader(500)
 	_, _ = fmt.Fprint(w, returnHTML)
 }
@@ -0,0 +1,132 @@
+package slimgo
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// File serves the file name with its Content-Type, Last-Modified and ETag.
+// Conditional and Range requests, including multiple ranges, are answered
+// by http.ServeContent. name is used as is, never pass unchecked client input.
+func (c *context) File(name string) {
+	c.serveFile(name, "")
+}
+
+// serveFile serves the file name, with the Content-Disposition header
+// disposition once the file is known to exist.
+func (c *context) serveFile(name, disposition string) {
+	f, err := os.Open(name)
+	if err != nil {
+		c.fileError(err)
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		c.fileError(err)
+		return
+	}
+	if fi.IsDir() {
+		c.String(http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	if disposition != "" {
+		c.response.Header().Set("Content-Disposition", disposition)
+	}
+	if c.response.Header().Get("ETag") == "" {
+		c.response.Header().Set("ETag", fmt.Sprintf(`W/"%x-%x"`, fi.ModTime().UnixNano(), fi.Size()))
+	}
+	http.ServeContent(c.response, c.request, fi.Name(), fi.ModTime(), f)
+}
+
+func (c *context) fileError(err error) {
+	switch {
+	case os.IsNotExist(err):
+		c.String(http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	case os.IsPermission(err):
+		c.String(http.StatusForbidden, http.StatusText(http.StatusForbidden))
+	default:
+		c.Logger().Error("serve file failed", F("error", err))
+		c.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+}
+
+// Attachment serves the file name as a download saved as filename, which may
+// contain non-ASCII characters like "报告.pdf".
+func (c *context) Attachment(name, filename string) {
+	if filename == "" {
+		filename = filepath.Base(name)
+	}
+	c.serveFile(name, contentDisposition("attachment", filename))
+}
+
+// ServeContent serves content like File, e.g. a file of an UploadStorage or
+// data in memory. name sets the Content-Type by its extension, modtime the
+// Last-Modified header unless it is zero.
+func (c *context) ServeContent(name string, modtime time.Time, content io.ReadSeeker) {
+	http.ServeContent(c.response, c.request, name, modtime, content)
+}
+
+// DataFromReader streams reader as the body, for generated content. A
+// negative contentLength sends the body chunked.
+func (c *context) DataFromReader(statusCode int, contentLength int64, contentType string, reader io.Reader, extraHeaders map[string]string) error {
+	h := c.response.Header()
+	for k, v := range extraHeaders {
+		h.Set(k, v)
+	}
+	if contentType != "" {
+		h.Set("Content-Type", contentType)
+	}
+	if contentLength >= 0 {
+		h.Set("Content-Length", strconv.FormatInt(contentLength, 10))
+	}
+	c.response.WriteHeader(statusCode)
+	_, err := io.Copy(c.response, reader)
+	return err
+}
+
+// contentDisposition formats a Content-Disposition header by RFC 6266: an
+// ASCII filename for old clients and an RFC 5987 encoded filename* with the
+// real name when it is not plain ASCII.
+func contentDisposition(kind, filename string) string {
+	var fallback strings.Builder
+	for _, r := range filename {
+		if r < 0x20 || r >= 0x7f || r == '"' || r == '\\' || r == '%' {
+			fallback.WriteByte('_')
+		} else {
+			fallback.WriteRune(r)
+		}
+	}
+
+	v := kind + `; filename="` + fallback.String() + `"`
+	if fallback.String() != filename {
+		v += "; filename*=UTF-8''" + encodeRFC5987(filename)
+	}
+	return v
+}
+
+// encodeRFC5987 percent-encodes s except for the attr-chars of RFC 5987.
+func encodeRFC5987(s string) string {
+	const hex = "0123456789ABCDEF"
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		if 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' ||
+			strings.IndexByte("!#$&+-.^_`|~", b) >= 0 {
+			sb.WriteByte(b)
+			continue
+		}
+		sb.WriteByte('%')
+		sb.WriteByte(hex[b>>4])
+		sb.WriteByte(hex[b&0xf])
+	}
+	return sb.String()
+}
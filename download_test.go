@@ -0,0 +1,196 @@
+package slimgo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"report.pdf", `attachment; filename="report.pdf"`},
+		{"my report.pdf", `attachment; filename="my report.pdf"`},
+		{"报告.pdf", `attachment; filename="__.pdf"; filename*=UTF-8''%E6%8A%A5%E5%91%8A.pdf`},
+		{"résumé.txt", `attachment; filename="r_sum_.txt"; filename*=UTF-8''r%C3%A9sum%C3%A9.txt`},
+		{`a"b.txt`, `attachment; filename="a_b.txt"; filename*=UTF-8''a%22b.txt`},
+		{`a\b.txt`, `attachment; filename="a_b.txt"; filename*=UTF-8''a%5Cb.txt`},
+		{"100%.txt", `attachment; filename="100_.txt"; filename*=UTF-8''100%25.txt`},
+		{"a\r\nSet-Cookie: x=1", `attachment; filename="a__Set-Cookie: x=1"; filename*=UTF-8''a%0D%0ASet-Cookie%3A%20x%3D1`},
+		{"tab\t.txt", `attachment; filename="tab_.txt"; filename*=UTF-8''tab%09.txt`},
+	}
+	for _, tt := range tests {
+		got := contentDisposition("attachment", tt.filename)
+		if got != tt.want {
+			t.Errorf("contentDisposition(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+		if strings.ContainsAny(got, "\r\n") {
+			t.Errorf("contentDisposition(%q) contains a line break", tt.filename)
+		}
+	}
+}
+
+func TestFileAndServeContent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slimgo_download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "digits.txt")
+	if err := ioutil.WriteFile(name, []byte("0123456789"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New()
+	s.SetMode(Release)
+	s.GET("/file", func(c Context) {
+		c.File(name)
+	})
+	s.GET("/missing", func(c Context) {
+		c.File(filepath.Join(dir, "missing.txt"))
+	})
+	s.GET("/dir", func(c Context) {
+		c.File(dir)
+	})
+	s.GET("/content", func(c Context) {
+		c.ServeContent("digits.txt", time.Time{}, bytes.NewReader([]byte("0123456789")))
+	})
+
+	tests := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		code    int
+		body    string
+		header  string
+		value   string
+	}{
+		{"file", "/file", nil, http.StatusOK, "0123456789", "Content-Type", "text/plain; charset=utf-8"},
+		{"file range", "/file", map[string]string{"Range": "bytes=2-4"}, http.StatusPartialContent, "234", "Content-Range", "bytes 2-4/10"},
+		{"file suffix range", "/file", map[string]string{"Range": "bytes=-3"}, http.StatusPartialContent, "789", "Content-Range", "bytes 7-9/10"},
+		{"file open range", "/file", map[string]string{"Range": "bytes=8-"}, http.StatusPartialContent, "89", "Content-Range", "bytes 8-9/10"},
+		{"file multiple ranges", "/file", map[string]string{"Range": "bytes=0-1,5-6"}, http.StatusPartialContent, "", "Content-Type", "multipart/byteranges"},
+		{"file unsatisfiable range", "/file", map[string]string{"Range": "bytes=20-30"}, http.StatusRequestedRangeNotSatisfiable, "", "Content-Range", "bytes */10"},
+		{"file missing", "/missing", nil, http.StatusNotFound, "", "", ""},
+		{"file directory", "/dir", nil, http.StatusNotFound, "", "", ""},
+		{"content", "/content", nil, http.StatusOK, "0123456789", "Content-Type", "text/plain; charset=utf-8"},
+		{"content range", "/content", map[string]string{"Range": "bytes=2-4"}, http.StatusPartialContent, "234", "Content-Range", "bytes 2-4/10"},
+		{"content unsatisfiable range", "/content", map[string]string{"Range": "bytes=10-"}, http.StatusRequestedRangeNotSatisfiable, "", "Content-Range", "bytes */10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, req)
+			if w.Code != tt.code {
+				t.Fatalf("status %d, want %d", w.Code, tt.code)
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("body %q, want %q", w.Body.String(), tt.body)
+			}
+			if tt.header != "" && !strings.HasPrefix(w.Header().Get(tt.header), tt.value) {
+				t.Errorf("%s %q, want %q", tt.header, w.Header().Get(tt.header), tt.value)
+			}
+		})
+	}
+
+	// the ETag of File makes conditional requests work
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/file", nil))
+	req := httptest.NewRequest(http.MethodGet, "/file", nil)
+	req.Header.Set("If-None-Match", w.Header().Get("ETag"))
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: status %d, want 304", w.Code)
+	}
+}
+
+func TestAttachment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slimgo_download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "data.csv")
+	if err := ioutil.WriteFile(name, []byte("a,b"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New()
+	s.SetMode(Release)
+	s.GET("/download", func(c Context) {
+		c.Attachment(name, "报告.csv")
+	})
+	s.GET("/default", func(c Context) {
+		c.Attachment(name, "")
+	})
+	s.GET("/missing", func(c Context) {
+		c.Attachment(filepath.Join(dir, "missing.csv"), "missing.csv")
+	})
+
+	tests := []struct {
+		path        string
+		code        int
+		disposition string
+	}{
+		{"/download", http.StatusOK, `attachment; filename="__.csv"; filename*=UTF-8''%E6%8A%A5%E5%91%8A.csv`},
+		{"/default", http.StatusOK, `attachment; filename="data.csv"`},
+		{"/missing", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code || w.Header().Get("Content-Disposition") != tt.disposition {
+			t.Errorf("%s: %d %q, want %d %q", tt.path, w.Code, w.Header().Get("Content-Disposition"), tt.code, tt.disposition)
+		}
+	}
+}
+
+func TestDataFromReader(t *testing.T) {
+	tests := []struct {
+		name   string
+		length int64
+		want   string
+	}{
+		{"known length", 5, "5"},
+		{"chunked", -1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.SetMode(Release)
+			var err error
+			s.GET("/data", func(c Context) {
+				err = c.DataFromReader(http.StatusAccepted, tt.length, "text/csv", strings.NewReader("a,b\n1"),
+					map[string]string{"Content-Disposition": `attachment; filename="a.csv"`})
+			})
+
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/data", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != http.StatusAccepted || w.Body.String() != "a,b\n1" {
+				t.Errorf("got %d %q", w.Code, w.Body.String())
+			}
+			if w.Header().Get("Content-Type") != "text/csv" || w.Header().Get("Content-Disposition") == "" {
+				t.Errorf("headers %v", w.Header())
+			}
+			if got := w.Header().Get("Content-Length"); got != tt.want {
+				t.Errorf("Content-Length %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
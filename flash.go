@@ -0,0 +1,130 @@
+package slimgo
+
+import (
+	"encoding/gob"
+	"errors"
+	"net/http"
+)
+
+// Flash is a message shown once on the next page, e.g. after a redirect.
+type Flash struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+const (
+	flashCookieName = "slimgo_flash"
+	// flashCookieMaxAge is long enough to follow a redirect.
+	flashCookieMaxAge = 300
+)
+
+func init() {
+	gob.Register(Flash{})
+}
+
+// flashState is the flash cookie of a request, only used without sessions.
+type flashState struct {
+	pending  []Flash
+	consumed bool
+}
+
+// Flash adds a message for the next request. It is kept in the session when
+// Server.UseSessions was called, otherwise in a signed cookie which needs
+// Server.SetCookieSecrets.
+func (c *context) Flash(kind, message string) error {
+	if c.server.sessionStore != nil {
+		c.Session().AddFlash(Flash{Kind: kind, Message: message})
+		return nil
+	}
+
+	secrets := c.server.cookieSecrets
+	if len(secrets) == 0 || secrets[0] == "" {
+		return errors.New("flash messages need sessions or a cookie secret")
+	}
+	state := c.flashState()
+	state.pending = append(state.pending, Flash{Kind: kind, Message: message})
+	return nil
+}
+
+// Flashes returns the messages added by the previous requests and removes them.
+func (c *context) Flashes() []Flash {
+	if c.server.sessionStore != nil {
+		values := c.Session().Flashes()
+		flashes := make([]Flash, 0, len(values))
+		for _, v := range values {
+			if f, ok := v.(Flash); ok {
+				flashes = append(flashes, f)
+			}
+		}
+		return flashes
+	}
+
+	state := c.flashState()
+	if state.consumed {
+		return nil
+	}
+	state.consumed = true
+	return c.flashCookie()
+}
+
+func (c *context) flashState() *flashState {
+	if c.flash == nil {
+		c.flash = &flashState{}
+		if rw, ok := c.response.(*responseWriter); ok {
+			rw.before(c.saveFlashes)
+		}
+	}
+	return c.flash
+}
+
+// flashCookie returns the messages of the flash cookie of the request.
+func (c *context) flashCookie() []Flash {
+	cookie := c.Cookie(flashCookieName)
+	if cookie == "" {
+		return nil
+	}
+	value, ok := verifyCookie(c.server.cookieSecrets, flashCookieName, cookie)
+	if !ok {
+		return nil
+	}
+	var flashes []Flash
+	if err := json.Unmarshal([]byte(value), &flashes); err != nil {
+		return nil
+	}
+	return flashes
+}
+
+// saveFlashes writes the pending messages to the flash cookie, or removes
+// the cookie once the messages were read.
+func (c *context) saveFlashes() {
+	state := c.flash
+	if state == nil {
+		return
+	}
+
+	flashes := state.pending
+	if !state.consumed {
+		// keep the unread messages of the previous request
+		flashes = append(c.flashCookie(), flashes...)
+	}
+	if len(flashes) == 0 {
+		if state.consumed && c.Cookie(flashCookieName) != "" {
+			_ = c.DeleteCookie(flashCookieName, "/")
+		}
+		return
+	}
+
+	b, err := json.Marshal(flashes)
+	if err != nil {
+		c.Logger().Error("save flash messages failed", F("error", err))
+		return
+	}
+	_ = c.SetHTTPCookie(&http.Cookie{
+		Name:     flashCookieName,
+		Value:    signCookie(c.server.cookieSecrets[0], flashCookieName, string(b), c.cookieExpires(flashCookieMaxAge)),
+		Path:     "/",
+		MaxAge:   flashCookieMaxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -29,6 +29,10 @@ func (logger) Infof(format string, fields ...interface{}) {
 	log.Printf("[INFO] "+format, fields...)
 }
 
+func (logger) Warnf(format string, fields ...interface{}) {
+	log.Printf("[WARN] "+format, fields...)
+}
+
 func (logger) Errorf(format string, fields ...interface{}) {
 	log.Printf("[ERROR] "+format, fields...)
 }
@@ -0,0 +1,267 @@
+package slimgo
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// defaultTrustedProxies trusts proxies on the same host only.
+var defaultTrustedProxies = []string{"127.0.0.0/8", "::1/128"}
+
+// SetTrustedProxies sets the proxies whose X-Forwarded-For, Forwarded,
+// X-Real-Ip, X-Forwarded-Proto and X-Forwarded-Host headers are trusted, as
+// CIDRs or single IPs. Loopback addresses are trusted by default, no
+// arguments trust nobody.
+func (s *Server) SetTrustedProxies(cidrs ...string) error {
+	nets, err := parseCIDRs(cidrs)
+	if err != nil {
+		return err
+	}
+	s.trustedProxies = nets
+	return nil
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", cidr)
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", cidr)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+func (s *Server) isTrustedProxy(ip net.IP) bool {
+	for _, n := range s.trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedHop is one element of a Forwarded header.
+type forwardedHop struct {
+	ip    net.IP
+	proto string
+	host  string
+}
+
+// remoteIP returns the IP of the peer of the connection.
+func (c *context) remoteIP() net.IP {
+	addr := strings.TrimSpace(c.request.RemoteAddr)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return net.ParseIP(addr)
+}
+
+// clientHop finds the client among the hops added by trusted proxies. The
+// hops are read right to left, the first one not from a trusted proxy is the
+// client, so a client can not spoof its IP by sending the headers itself.
+// It returns nil when the request did not come through a trusted proxy.
+func (c *context) clientHop() *forwardedHop {
+	remote := c.remoteIP()
+	if remote == nil || !c.server.isTrustedProxy(remote) {
+		return nil
+	}
+
+	var hops []forwardedHop
+	if v := c.request.Header["Forwarded"]; len(v) > 0 {
+		hops = parseForwarded(strings.Join(v, ","))
+	} else {
+		var ips []string
+		if v := c.request.Header["X-Forwarded-For"]; len(v) > 0 {
+			ips = headerList(v)
+		} else if v := c.request.Header.Get("X-Real-Ip"); v != "" {
+			ips = []string{v}
+		}
+		// each proxy appends to all of the headers, so they line up from the right
+		protos := headerList(c.request.Header["X-Forwarded-Proto"])
+		hosts := headerList(c.request.Header["X-Forwarded-Host"])
+		for i, s := range ips {
+			fromRight := len(ips) - i
+			hops = append(hops, forwardedHop{
+				ip:    parseForwardedIP(s),
+				proto: listItem(protos, len(protos)-fromRight),
+				host:  listItem(hosts, len(hosts)-fromRight),
+			})
+		}
+	}
+
+	client := &forwardedHop{ip: remote}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if hops[i].ip == nil {
+			// unknown or garbage, nothing left of it can be trusted
+			break
+		}
+		client = &hops[i]
+		if !c.server.isTrustedProxy(hops[i].ip) {
+			break
+		}
+	}
+	return client
+}
+
+// ClientIP returns the IP of the client. Forwarding headers are only used
+// when the request comes from a proxy set by Server.SetTrustedProxies.
+func (c *context) ClientIP() string {
+	if hop := c.clientHop(); hop != nil {
+		return hop.ip.String()
+	}
+	if ip := c.remoteIP(); ip != nil {
+		return ip.String()
+	}
+	return "127.0.0.1"
+}
+
+// Scheme returns "https" or "http" as requested by the client, taken from
+// the Forwarded or X-Forwarded-Proto header of a trusted proxy.
+func (c *context) Scheme() string {
+	if hop := c.clientHop(); hop != nil {
+		if proto := strings.ToLower(hop.proto); proto == "http" || proto == "https" {
+			return proto
+		}
+	}
+	if c.request.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// Host returns the host requested by the client, taken from the Forwarded
+// or X-Forwarded-Host header of a trusted proxy.
+func (c *context) Host() string {
+	if hop := c.clientHop(); hop != nil && validHost(hop.host) {
+		return hop.host
+	}
+	return c.request.Host
+}
+
+// headerList splits the comma separated values of a header.
+func headerList(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	list := strings.Split(strings.Join(values, ","), ",")
+	for i := range list {
+		list[i] = strings.TrimSpace(list[i])
+	}
+	return list
+}
+
+func listItem(list []string, i int) string {
+	if i < 0 || i >= len(list) {
+		return ""
+	}
+	return list[i]
+}
+
+// validHost reports whether host is a host name, an IPv4 address or a
+// bracketed IPv6 address, with an optional port.
+func validHost(host string) bool {
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.Contains(host[i:], "]") {
+		port := host[i+1:]
+		if port == "" || len(port) > 5 || strings.Trim(port, "0123456789") != "" {
+			return false
+		}
+		host = host[:i]
+	}
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		ip := net.ParseIP(host[1 : len(host)-1])
+		return ip != nil && ip.To4() == nil
+	}
+	if host == "" || len(host) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// parseForwarded parses a Forwarded header by RFC 7239, e.g.
+// `for=192.0.2.60;proto=https, for="[2001:db8::1]:4711"`.
+func parseForwarded(header string) []forwardedHop {
+	var hops []forwardedHop
+	for _, element := range splitQuoted(header, ',') {
+		var hop forwardedHop
+		for _, pair := range splitQuoted(element, ';') {
+			i := strings.IndexByte(pair, '=')
+			if i < 0 {
+				continue
+			}
+			key := strings.ToLower(strings.TrimSpace(pair[:i]))
+			value := strings.Trim(strings.TrimSpace(pair[i+1:]), `"`)
+			switch key {
+			case "for":
+				hop.ip = parseForwardedIP(value)
+			case "proto":
+				hop.proto = value
+			case "host":
+				hop.host = value
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// parseForwardedIP parses "192.0.2.60", "192.0.2.60:80", "[2001:db8::1]" or
+// "[2001:db8::1]:4711". It returns nil for "unknown" and obfuscated names.
+func parseForwardedIP(s string) net.IP {
+	s = strings.TrimSpace(s)
+	if ip := net.ParseIP(s); ip != nil {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		return net.ParseIP(host)
+	}
+	return net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+}
+
+// splitQuoted splits s at sep outside of double quotes.
+func splitQuoted(s string, sep byte) []string {
+	var (
+		parts  []string
+		quoted bool
+		start  int
+	)
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case '\\':
+			if quoted {
+				i++
+			}
+		case sep:
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
@@ -0,0 +1,169 @@
+package slimgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	s := New()
+	s.SetMode(Release)
+	if err := s.SetTrustedProxies("10.0.0.0/8", "2001:db8:ffff::/48"); err != nil {
+		t.Fatal(err)
+	}
+	s.GET("/ip", func(c Context) {
+		c.String(http.StatusOK, c.ClientIP()+" "+c.Scheme()+" "+c.Host())
+	})
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{"direct", "203.0.113.7:1234", nil, "203.0.113.7 http example.com"},
+		{"untrusted peer sends XFF", "203.0.113.7:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1"}, "203.0.113.7 http example.com"},
+		{"untrusted peer sends Forwarded", "203.0.113.7:1234",
+			map[string]string{"Forwarded": "for=198.51.100.1;proto=https;host=evil.com"}, "203.0.113.7 http example.com"},
+		{"untrusted peer sends X-Real-Ip", "203.0.113.7:1234",
+			map[string]string{"X-Real-Ip": "198.51.100.1"}, "203.0.113.7 http example.com"},
+		{"trusted proxy XFF", "10.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1"}, "198.51.100.1 http example.com"},
+		{"spoofed XFF left of client", "10.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1"}, "198.51.100.1 http example.com"},
+		{"spoofed XFF behind proxy chain", "10.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1, 10.0.0.2"}, "198.51.100.1 http example.com"},
+		{"garbage XFF hop", "10.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "1.2.3.4, nonsense"}, "10.0.0.1 http example.com"},
+		{"only trusted hops", "10.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, "10.0.0.3 http example.com"},
+		{"trusted proxy X-Real-Ip", "10.0.0.1:1234",
+			map[string]string{"X-Real-Ip": "198.51.100.1"}, "198.51.100.1 http example.com"},
+		{"forwarded proto and host", "10.0.0.1:1234",
+			map[string]string{"Forwarded": "for=198.51.100.1;proto=https;host=app.example.org"}, "198.51.100.1 https app.example.org"},
+		{"forwarded spoofed chain", "10.0.0.1:1234",
+			map[string]string{"Forwarded": "for=1.2.3.4;host=evil.com, for=198.51.100.1;host=app.example.org"}, "198.51.100.1 http app.example.org"},
+		{"forwarded wins over XFF", "10.0.0.1:1234",
+			map[string]string{"Forwarded": "for=198.51.100.1", "X-Forwarded-For": "1.2.3.4"}, "198.51.100.1 http example.com"},
+		{"forwarded quoted IPv4 with port", "10.0.0.1:1234",
+			map[string]string{"Forwarded": `for="198.51.100.1:8080"`}, "198.51.100.1 http example.com"},
+		{"forwarded quoted IPv6", "10.0.0.1:1234",
+			map[string]string{"Forwarded": `for="[2001:db8::1]:4711"`}, "2001:db8::1 http example.com"},
+		{"forwarded quoted comma", "10.0.0.1:1234",
+			map[string]string{"Forwarded": `for=198.51.100.1;proto=https;host="a,b", for=10.0.0.2`}, "198.51.100.1 https example.com"},
+		{"forwarded host with port", "10.0.0.1:1234",
+			map[string]string{"Forwarded": `for=198.51.100.1;host="app.example.org:8443"`}, "198.51.100.1 http app.example.org:8443"},
+		{"forwarded IPv6 host", "10.0.0.1:1234",
+			map[string]string{"Forwarded": `for=198.51.100.1;host="[2001:db8::1]:8080"`}, "198.51.100.1 http [2001:db8::1]:8080"},
+		{"forwarded host with path", "10.0.0.1:1234",
+			map[string]string{"Forwarded": `for=198.51.100.1;host="evil.com/x"`}, "198.51.100.1 http example.com"},
+		{"forwarded host of proxy hop", "10.0.0.1:1234",
+			map[string]string{"Forwarded": "for=198.51.100.1;host=app.example.org, for=10.0.0.2;proto=https;host=inner.local"}, "198.51.100.1 http app.example.org"},
+		{"forwarded unknown", "10.0.0.1:1234",
+			map[string]string{"Forwarded": "for=198.51.100.1, for=unknown"}, "10.0.0.1 http example.com"},
+		{"forwarded obfuscated", "10.0.0.1:1234",
+			map[string]string{"Forwarded": "for=_hidden"}, "10.0.0.1 http example.com"},
+		{"IPv6 trusted proxy", "[2001:db8:ffff::1]:1234",
+			map[string]string{"Forwarded": `for="[2001:db8::1]", for="[2001:db8:ffff::2]"`}, "2001:db8::1 http example.com"},
+		{"IPv6 untrusted peer", "[2001:db8::9]:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1"}, "2001:db8::9 http example.com"},
+		{"X-Forwarded-Proto and Host", "10.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "app.example.org"}, "198.51.100.1 https app.example.org"},
+		{"spoofed X-Forwarded-Proto and Host", "10.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1", "X-Forwarded-Proto": "https, http", "X-Forwarded-Host": "evil.com, app.example.org"}, "198.51.100.1 http app.example.org"},
+		{"X-Forwarded-Proto and Host behind proxy chain", "10.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1, 10.0.0.2", "X-Forwarded-Proto": "https, http", "X-Forwarded-Host": "app.example.org, inner.local"}, "198.51.100.1 https app.example.org"},
+		{"X-Forwarded-Proto missing for client hop", "10.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1, 10.0.0.2", "X-Forwarded-Proto": "https"}, "198.51.100.1 http example.com"},
+		{"X-Real-Ip with proto", "10.0.0.1:1234",
+			map[string]string{"X-Real-Ip": "198.51.100.1", "X-Forwarded-Proto": "http, https"}, "198.51.100.1 https example.com"},
+		{"invalid X-Forwarded-Host", "10.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Forwarded-Host": `evil.com"><script>`}, "198.51.100.1 http example.com"},
+		{"untrusted peer sends X-Forwarded-Host", "203.0.113.7:1234",
+			map[string]string{"X-Forwarded-Host": "evil.com", "X-Forwarded-Proto": "https"}, "203.0.113.7 http example.com"},
+		{"invalid proto ignored", "10.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Forwarded-Proto": "javascript"}, "198.51.100.1 http example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/ip", nil)
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, req)
+			if got := strings.TrimSpace(w.Body.String()); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseForwarded(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"for=192.0.2.60", []string{"192.0.2.60||"}},
+		{"For=192.0.2.60;Proto=https;Host=a.com", []string{"192.0.2.60|https|a.com"}},
+		{`for="[2001:db8::1]:4711"`, []string{"2001:db8::1||"}},
+		{`for="[2001:db8::1]"`, []string{"2001:db8::1||"}},
+		{"for=192.0.2.60, for=198.51.100.17", []string{"192.0.2.60||", "198.51.100.17||"}},
+		{`for=192.0.2.60;host="x;y,z"`, []string{"192.0.2.60||x;y,z"}},
+		{"for=unknown", []string{"<nil>||"}},
+		{"by=192.0.2.1", []string{"<nil>||"}},
+		{"garbage", []string{"<nil>||"}},
+	}
+	for _, tt := range tests {
+		hops := parseForwarded(tt.header)
+		var got []string
+		for _, h := range hops {
+			got = append(got, h.ip.String()+"|"+h.proto+"|"+h.host)
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("parseForwarded(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestValidHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", true},
+		{"example.com:8080", true},
+		{"localhost", true},
+		{"my_host.local", true},
+		{"192.0.2.1", true},
+		{"192.0.2.1:443", true},
+		{"[2001:db8::1]", true},
+		{"[2001:db8::1]:443", true},
+		{"", false},
+		{":80", false},
+		{"example.com:", false},
+		{"example.com:http", false},
+		{"example.com:123456", false},
+		{"a,b", false},
+		{"evil.com/path", false},
+		{"user@evil.com", false},
+		{"evil.com\\x", false},
+		{"a..b", false},
+		{".example.com", false},
+		{"2001:db8::1", false},
+		{"[192.0.2.1]", false},
+		{"[not-an-ip]", false},
+		{"exa mple.com", false},
+		{"例子.com", false},
+		{strings.Repeat("a", 64) + ".com", false},
+	}
+	for _, tt := range tests {
+		if got := validHost(tt.host); got != tt.want {
+			t.Errorf("validHost(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
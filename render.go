@@ -0,0 +1,221 @@
+package slimgo
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/gitwillsky/slimgo/binding"
+)
+
+// streamFlushEvery is the number of values JSONStream writes between flushes.
+const streamFlushEvery = 32
+
+// JSONIterator returns the next value of a JSON stream.
+// ok is false when there are no more values.
+type JSONIterator func() (value interface{}, ok bool, err error)
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	// do not keep huge buffers in the pool
+	if buf.Cap() <= 64*1024 {
+		bufferPool.Put(buf)
+	}
+}
+
+// defaultSecureJSONPrefix is written before SecureJSON bodies unless the
+// server sets another prefix.
+const defaultSecureJSONPrefix = "while(1);"
+
+// reJSONPCallback matches safe JSONP callback names like "cb" or "app.handlers.cb".
+var reJSONPCallback = regexp.MustCompile(`^[a-zA-Z_$][0-9a-zA-Z_$]*(\.[a-zA-Z_$][0-9a-zA-Z_$]*)*$`)
+
+const maxJSONPCallbackLen = 128
+
+// jsonRender describes a variant of the JSON output.
+type jsonRender struct {
+	contentType string
+	indent      bool
+	ascii       bool
+	prefix      string
+	suffix      string
+}
+
+// renderJSON encodes data into a buffer, so nothing is written on errors,
+// and then writes the status and the body.
+func (c *context) renderJSON(statusCode int, data interface{}, r jsonRender) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	buf.WriteString(r.prefix)
+	enc := json.NewEncoder(buf)
+	if r.indent {
+		enc.SetIndent("", "    ")
+	}
+	if err := enc.Encode(data); err != nil {
+		http.Error(c.response, err.Error(), 500)
+		return
+	}
+	body := buf.Bytes()
+	if r.ascii {
+		body = toASCII(body)
+	}
+
+	if r.contentType == "" {
+		r.contentType = "application/json;charset=utf-8"
+	}
+	c.response.Header().Set("Content-Type", r.contentType)
+	c.WriteResponseHeader(statusCode)
+	_, _ = c.response.Write(body)
+	if r.suffix != "" {
+		_, _ = c.response.Write([]byte(r.suffix))
+	}
+}
+
+// IndentedJSON writes data as indented JSON.
+func (c *context) IndentedJSON(statusCode int, data interface{}) {
+	c.renderJSON(statusCode, data, jsonRender{indent: true})
+}
+
+// JSONP wraps the JSON of data in the function named by the "callback" query
+// parameter. Without a callback it writes plain JSON, an invalid callback
+// name results in 400.
+func (c *context) JSONP(statusCode int, data interface{}) {
+	callback := c.request.URL.Query().Get("callback")
+	if callback == "" {
+		c.renderJSON(statusCode, data, jsonRender{})
+		return
+	}
+	if len(callback) > maxJSONPCallbackLen || !reJSONPCallback.MatchString(callback) {
+		http.Error(c.response, "invalid jsonp callback", 400)
+		return
+	}
+
+	c.response.Header().Set("X-Content-Type-Options", "nosniff")
+	c.renderJSON(statusCode, data, jsonRender{
+		contentType: "application/javascript;charset=utf-8",
+		prefix:      "/**/" + callback + "(",
+		suffix:      ");",
+	})
+}
+
+// SecureJSON prefixes the JSON of data with "while(1);" (or the prefix set by
+// Server.SetSecureJSONPrefix) to prevent JSON hijacking of arrays.
+func (c *context) SecureJSON(statusCode int, data interface{}) {
+	prefix := c.server.secureJSONPrefix
+	if prefix == "" {
+		prefix = defaultSecureJSONPrefix
+	}
+	c.renderJSON(statusCode, data, jsonRender{prefix: prefix})
+}
+
+// AsciiJSON writes data as JSON with all non-ASCII characters escaped as \uXXXX.
+func (c *context) AsciiJSON(statusCode int, data interface{}) {
+	c.renderJSON(statusCode, data, jsonRender{ascii: true})
+}
+
+// toASCII escapes the non-ASCII characters of encoded JSON. They can only
+// appear inside strings, where \uXXXX escapes are valid.
+func toASCII(b []byte) []byte {
+	var out bytes.Buffer
+	for _, r := range string(b) {
+		switch {
+		case r < utf8.RuneSelf:
+			out.WriteByte(byte(r))
+		case r > 0xFFFF:
+			r1, r2 := utf16.EncodeRune(r)
+			fmt.Fprintf(&out, "\\u%04x\\u%04x", r1, r2)
+		default:
+			fmt.Fprintf(&out, "\\u%04x", r)
+		}
+	}
+	return out.Bytes()
+}
+
+// JSONStream writes the values of iter as newline-delimited JSON when the
+// request accepts application/x-ndjson, and as a JSON array otherwise.
+// The response is flushed in chunks, so values are never held in memory together.
+//
+// An error of the first value results in a 500 response. Later errors can not
+// change the status any more, they end the stream early (a JSON array is left
+// unterminated) and are returned.
+func (c *context) JSONStream(statusCode int, iter JSONIterator) error {
+	ndjson := strings.Contains(c.request.Header.Get("Accept"), binding.MIMENDJSON)
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	for i := 0; ; i++ {
+		value, ok, err := iter()
+		if err == nil && ok {
+			buf.Reset()
+			err = json.NewEncoder(buf).Encode(value)
+		}
+		if err != nil {
+			if i == 0 {
+				http.Error(c.response, err.Error(), 500)
+			}
+			return err
+		}
+
+		if i == 0 {
+			if ndjson {
+				c.response.Header().Set("Content-Type", binding.MIMENDJSON+";charset=utf-8")
+			} else {
+				c.response.Header().Set("Content-Type", "application/json;charset=utf-8")
+			}
+			c.WriteResponseHeader(statusCode)
+			if !ndjson {
+				_, _ = c.response.Write([]byte{'['})
+			}
+		}
+
+		if !ok {
+			break
+		}
+
+		out := buf.Bytes()
+		if !ndjson {
+			// the encoder ends each value with a newline
+			out = bytes.TrimSuffix(out, []byte{'\n'})
+			if i > 0 {
+				_, _ = c.response.Write([]byte{','})
+			}
+		}
+		if _, err := c.response.Write(out); err != nil {
+			return err
+		}
+		if (i+1)%streamFlushEvery == 0 {
+			c.flush()
+		}
+	}
+
+	if !ndjson {
+		_, _ = c.response.Write([]byte{']'})
+	}
+	c.flush()
+	return nil
+}
+
+// flush sends the buffered response if the ResponseWriter supports it.
+func (c *context) flush() {
+	if f, ok := c.response.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,105 @@
+package slimgo
+
+import (
+	stdjson "encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type renderItem struct {
+	Name string `json:"name"`
+}
+
+func TestJSONPCallback(t *testing.T) {
+	s := New()
+	s.SetMode(Release)
+	s.GET("/jsonp", func(c Context) {
+		c.JSONP(http.StatusOK, renderItem{Name: "a"})
+	})
+
+	tests := []struct {
+		callback string
+		code     int
+		body     string
+	}{
+		{"", http.StatusOK, `{"name":"a"}`},
+		{"cb", http.StatusOK, `/**/cb({"name":"a"}` + "\n);"},
+		{"app.handlers.cb_1", http.StatusOK, `/**/app.handlers.cb_1({"name":"a"}` + "\n);"},
+		{"$jq", http.StatusOK, `/**/$jq({"name":"a"}` + "\n);"},
+		{"alert(1)//", http.StatusBadRequest, ""},
+		{"a;b", http.StatusBadRequest, ""},
+		{"a b", http.StatusBadRequest, ""},
+		{"1cb", http.StatusBadRequest, ""},
+		{"a..b", http.StatusBadRequest, ""},
+		{"a.", http.StatusBadRequest, ""},
+		{".a", http.StatusBadRequest, ""},
+		{"a[0]", http.StatusBadRequest, ""},
+		{"<script>", http.StatusBadRequest, ""},
+		{"cb ", http.StatusBadRequest, ""},
+		{"ñ", http.StatusBadRequest, ""},
+		{strings.Repeat("a", maxJSONPCallbackLen+1), http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/jsonp?callback="+url.QueryEscape(tt.callback), nil))
+		if w.Code != tt.code {
+			t.Errorf("callback %q: status %d, want %d", tt.callback, w.Code, tt.code)
+			continue
+		}
+		if tt.body != "" && strings.TrimSpace(w.Body.String()) != tt.body {
+			t.Errorf("callback %q: body %q, want %q", tt.callback, w.Body.String(), tt.body)
+		}
+		if tt.callback != "" && tt.code == http.StatusOK {
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/javascript") {
+				t.Errorf("callback %q: Content-Type %q", tt.callback, ct)
+			}
+			if w.Header().Get("X-Content-Type-Options") != "nosniff" {
+				t.Errorf("callback %q: missing nosniff", tt.callback)
+			}
+		}
+	}
+}
+
+func TestToASCII(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`"plain"`, `"plain"`},
+		{`"a\"b\\c"`, `"a\"b\\c"`},
+		{"\"h\u00e9llo\"", `"h\u00e9llo"`},
+		{"\"\u4e2d\u6587\"", `"\u4e2d\u6587"`},
+		{"\"\U0001F600\"", `"\ud83d\ude00"`},
+		{"\"\U0001D11Ex\"", `"\ud834\udd1ex"`},
+		{"\"\u2028\u2029\"", `"\u2028\u2029"`},
+		{"\"\xff\"", `"\ufffd"`},
+	}
+	for _, tt := range tests {
+		if got := string(toASCII([]byte(tt.in))); got != tt.want {
+			t.Errorf("toASCII(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAsciiJSON(t *testing.T) {
+	s := New()
+	s.SetMode(Release)
+	want := "héllo 中文 😀 <tag>"
+	s.GET("/ascii", func(c Context) {
+		c.AsciiJSON(http.StatusOK, renderItem{Name: want})
+	})
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ascii", nil))
+	for _, b := range w.Body.Bytes() {
+		if b >= 0x80 {
+			t.Fatalf("non-ASCII byte in %q", w.Body.String())
+		}
+	}
+	var got renderItem
+	if err := stdjson.Unmarshal(w.Body.Bytes(), &got); err != nil || got.Name != want {
+		t.Errorf("decoded %q, %v; want %q", got.Name, err, want)
+	}
+}
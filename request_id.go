@@ -0,0 +1,89 @@
+package slimgo
+
+import (
+	stdctx "context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"regexp"
+)
+
+// RequestIDHeader is the default header of the request ID.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey is the Data key of the request ID set by the RequestID middleware.
+const RequestIDKey = "request_id"
+
+// requestIDContextKey is the key of the request ID in req.Context().
+type requestIDContextKey struct{}
+
+// reRequestID limits the IDs accepted from clients, so they are safe to log.
+var reRequestID = regexp.MustCompile(`^[A-Za-z0-9._:+/=-]{1,128}$`)
+
+// RequestIDOptions configures the RequestID middleware.
+type RequestIDOptions struct {
+	// Header is the request and response header of the ID, "X-Request-ID" by default.
+	Header string
+	// Generator creates new IDs, 32 random hex characters by default.
+	Generator func() string
+	// IgnoreIncoming always generates a new ID instead of using the one sent
+	// by the client or a proxy.
+	IgnoreIncoming bool
+}
+
+// RequestID returns a middleware which gives every request an ID. It reuses
+// a valid ID from the request header, stores it in Data under RequestIDKey,
+// echoes it in the response header and puts it in Request().Context(), where
+// RequestIDFromContext finds it for outbound requests.
+func RequestID(opts RequestIDOptions) Handler {
+	if opts.Header == "" {
+		opts.Header = RequestIDHeader
+	}
+	if opts.Generator == nil {
+		opts.Generator = newRequestID
+	}
+
+	return func(c Context) {
+		var id string
+		if !opts.IgnoreIncoming {
+			if v := c.Request().Header.Get(opts.Header); reRequestID.MatchString(v) {
+				id = v
+			}
+		}
+		if id == "" {
+			id = opts.Generator()
+		}
+
+		c.PutData(RequestIDKey, id)
+		c.ResponseHeader().Set(opts.Header, id)
+		if ctx, ok := c.(*context); ok {
+			ctx.request = ctx.request.WithContext(stdctx.WithValue(ctx.request.Context(), requestIDContextKey{}, id))
+			ctx.log = nil
+		}
+	}
+}
+
+// RequestIDFromContext returns the request ID stored by the RequestID
+// middleware in ctx, "" if there is none.
+func RequestIDFromContext(ctx stdctx.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// requestID returns the ID set by the RequestID middleware, or "".
+func (c *context) requestID() string {
+	if c.data == nil {
+		return ""
+	}
+	v, _ := c.data.Load(RequestIDKey)
+	id, _ := v.(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -9,6 +9,15 @@ type ResponseWriter interface {
 	Written() bool
 }
 
+// statusWriter is implemented by the ResponseWriter of a Context, it is not
+// part of ResponseWriter so other implementations keep working.
+type statusWriter interface {
+	// Status returns the status code written, 0 if nothing was written yet.
+	Status() int
+	// Size returns the number of body bytes written.
+	Size() int
+}
+
 // NewResponseWriter creates a ResponseWriter that wraps an http.ResponseWriter
 func newResponseWriter(res http.ResponseWriter) ResponseWriter {
 	return &responseWriter{
@@ -20,9 +29,27 @@ func newResponseWriter(res http.ResponseWriter) ResponseWriter {
 type responseWriter struct {
 	res  http.ResponseWriter
 	code int
+	size int
+	// beforeWrite is called once right before the header is written,
+	// the last chance to change it.
+	beforeWrite []func()
+}
+
+// before registers fn to be called before the response header is written.
+func (r *responseWriter) before(fn func()) {
+	r.beforeWrite = append(r.beforeWrite, fn)
+}
+
+func (r *responseWriter) runBeforeWrite() {
+	hooks := r.beforeWrite
+	r.beforeWrite = nil
+	for _, fn := range hooks {
+		fn()
+	}
 }
 
 func (r *responseWriter) WriteHeader(code int) {
+	r.runBeforeWrite()
 	r.code = code
 	r.res.WriteHeader(code)
 }
@@ -31,13 +58,35 @@ func (r *responseWriter) Written() bool {
 	return r.code != 0
 }
 
+func (r *responseWriter) Status() int {
+	return r.code
+}
+
+func (r *responseWriter) Size() int {
+	return r.size
+}
+
 func (r *responseWriter) Write(b []byte) (int, error) {
 	if r.code == 0 {
+		r.runBeforeWrite()
 		r.code = http.StatusOK
 	}
-	return r.res.Write(b)
+	n, err := r.res.Write(b)
+	r.size += n
+	return n, err
 }
 
 func (r *responseWriter) Header() http.Header {
 	return r.res.Header()
 }
+
+// Flush sends any buffered data to the client if the underlying writer supports it.
+func (r *responseWriter) Flush() {
+	if f, ok := r.res.(http.Flusher); ok {
+		if r.code == 0 {
+			r.runBeforeWrite()
+			r.code = http.StatusOK
+		}
+		f.Flush()
+	}
+}
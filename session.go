@@ -0,0 +1,269 @@
+package slimgo
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrSessionNotFound is returned by a SessionStore when a session does not
+// exist or has expired.
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionStore loads and saves the values of sessions.
+//
+// The token is what the session cookie holds: the session ID for server-side
+// stores like MemoryStore and FileStore, the encrypted values for CookieStore.
+type SessionStore interface {
+	// Load returns the session ID and values of token, or ErrSessionNotFound.
+	Load(token string) (id string, values map[string]interface{}, err error)
+	// Save stores values for at least ttl and returns the token of the session.
+	Save(id string, values map[string]interface{}, ttl time.Duration) (token string, err error)
+	// Delete removes the session.
+	Delete(id string) error
+}
+
+// SessionOptions configures the sessions enabled by Server.UseSessions.
+type SessionOptions struct {
+	// CookieName is the name of the session cookie, "slimgo_session" by default.
+	CookieName string
+	// CookiePath is the path of the session cookie, "/" by default.
+	CookiePath string
+	// IdleTimeout ends sessions without requests for this long, 30 minutes by default.
+	IdleTimeout time.Duration
+	// AbsoluteTimeout ends sessions this long after they were created, 24 hours by default.
+	AbsoluteTimeout time.Duration
+}
+
+// reserved session keys
+const (
+	sessionCreatedKey  = "_slimgo/created"
+	sessionAccessedKey = "_slimgo/accessed"
+	sessionFlashesKey  = "_slimgo/flashes"
+)
+
+// sessionTouchInterval is how often the access time of an unchanged session
+// is saved, so not every request writes to the store. A quarter of a shorter
+// IdleTimeout is used instead.
+const sessionTouchInterval = time.Minute
+
+func touchInterval(idle time.Duration) time.Duration {
+	if idle/4 < sessionTouchInterval {
+		return idle / 4
+	}
+	return sessionTouchInterval
+}
+
+func init() {
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+}
+
+// UseSessions enables Context.Session with store.
+func (s *Server) UseSessions(store SessionStore, options ...SessionOptions) {
+	var opts SessionOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.CookieName == "" {
+		opts.CookieName = "slimgo_session"
+	}
+	if opts.CookiePath == "" {
+		opts.CookiePath = "/"
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = 30 * time.Minute
+	}
+	if opts.AbsoluteTimeout <= 0 {
+		opts.AbsoluteTimeout = 24 * time.Hour
+	}
+
+	s.sessionStore = store
+	s.sessionOptions = opts
+}
+
+// Session is the session of a request. It is saved when the response header
+// is written.
+type Session struct {
+	id     string
+	values map[string]interface{}
+
+	// oldID is the ID before RegenerateID, deleted from the store on save.
+	oldID     string
+	dirty     bool
+	destroyed bool
+}
+
+// ID returns the session ID.
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Get returns the value of key, or nil.
+func (s *Session) Get(key string) interface{} {
+	return s.values[key]
+}
+
+// Set sets the value of key. Values of custom types must be registered with gob.Register.
+func (s *Session) Set(key string, value interface{}) {
+	s.values[key] = value
+	s.dirty = true
+}
+
+// Delete removes key from the session.
+func (s *Session) Delete(key string) {
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.dirty = true
+	}
+}
+
+// AddFlash adds a value which is removed by the next call to Flashes.
+func (s *Session) AddFlash(value interface{}) {
+	flashes, _ := s.values[sessionFlashesKey].([]interface{})
+	s.values[sessionFlashesKey] = append(flashes, value)
+	s.dirty = true
+}
+
+// Flashes returns and removes the values added by AddFlash.
+func (s *Session) Flashes() []interface{} {
+	flashes, _ := s.values[sessionFlashesKey].([]interface{})
+	if len(flashes) > 0 {
+		delete(s.values, sessionFlashesKey)
+		s.dirty = true
+	}
+	return flashes
+}
+
+// RegenerateID gives the session a new ID and keeps its values. Call it on
+// login and privilege changes to prevent session fixation.
+func (s *Session) RegenerateID() {
+	if s.oldID == "" {
+		s.oldID = s.id
+	}
+	s.id = newSessionID()
+	s.values[sessionCreatedKey] = time.Now().Unix()
+	s.dirty = true
+}
+
+// Destroy removes the session from the store and the client, e.g. on logout.
+func (s *Session) Destroy() {
+	s.values = make(map[string]interface{})
+	s.destroyed = true
+	s.dirty = true
+}
+
+func newSession() *Session {
+	now := time.Now().Unix()
+	return &Session{
+		id: newSessionID(),
+		values: map[string]interface{}{
+			sessionCreatedKey:  now,
+			sessionAccessedKey: now,
+		},
+	}
+}
+
+func newSessionID() string {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Session returns the session of the request, a new one when the client has
+// none or it timed out. It panics if Server.UseSessions was not called.
+func (c *context) Session() *Session {
+	if c.session != nil {
+		return c.session
+	}
+	store, opts := c.server.sessionStore, c.server.sessionOptions
+	if store == nil {
+		panic(errors.New("sessions are not enabled, call Server.UseSessions first"))
+	}
+
+	c.session = c.loadSession(store, opts)
+	if rw, ok := c.response.(*responseWriter); ok {
+		rw.before(c.saveSession)
+	}
+	return c.session
+}
+
+func (c *context) loadSession(store SessionStore, opts SessionOptions) *Session {
+	token := c.Cookie(opts.CookieName)
+	if token == "" {
+		return newSession()
+	}
+
+	id, values, err := store.Load(token)
+	if err != nil {
+		if err != ErrSessionNotFound {
+			c.Logger().Error("load session failed", F("error", err))
+		}
+		return newSession()
+	}
+
+	now := time.Now()
+	created, _ := values[sessionCreatedKey].(int64)
+	accessed, _ := values[sessionAccessedKey].(int64)
+	if now.Sub(time.Unix(created, 0)) > opts.AbsoluteTimeout || now.Sub(time.Unix(accessed, 0)) > opts.IdleTimeout {
+		_ = store.Delete(id)
+		s := newSession()
+		s.dirty = true // replace the cookie
+		return s
+	}
+
+	s := &Session{id: id, values: values}
+	if now.Sub(time.Unix(accessed, 0)) > touchInterval(opts.IdleTimeout) {
+		s.values[sessionAccessedKey] = now.Unix()
+		s.dirty = true
+	}
+	return s
+}
+
+// saveSession writes a changed session to the store and the cookie. It runs
+// before the response header is written or after the handlers returned.
+func (c *context) saveSession() {
+	s := c.session
+	if s == nil || !s.dirty {
+		return
+	}
+	s.dirty = false
+
+	store, opts := c.server.sessionStore, c.server.sessionOptions
+	if s.oldID != "" {
+		_ = store.Delete(s.oldID)
+		s.oldID = ""
+	}
+	if s.destroyed {
+		if err := store.Delete(s.id); err != nil {
+			c.Logger().Error("delete session failed", F("error", err))
+		}
+		_ = c.DeleteCookie(opts.CookieName, opts.CookiePath)
+		return
+	}
+
+	created, _ := s.values[sessionCreatedKey].(int64)
+	ttl := time.Until(time.Unix(created, 0).Add(opts.AbsoluteTimeout))
+	if ttl > opts.IdleTimeout {
+		ttl = opts.IdleTimeout
+	}
+
+	token, err := store.Save(s.id, s.values, ttl)
+	if err != nil {
+		c.Logger().Error("save session failed", F("error", err))
+		return
+	}
+	_ = c.SetHTTPCookie(&http.Cookie{
+		Name:     opts.CookieName,
+		Value:    token,
+		Path:     opts.CookiePath,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
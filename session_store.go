@@ -0,0 +1,334 @@
+package slimgo
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionData is the gob encoded form of a session.
+type sessionData struct {
+	ID      string
+	Values  map[string]interface{}
+	Expires time.Time
+}
+
+func encodeSession(data *sessionData) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeSession(b []byte) (*sessionData, error) {
+	var data sessionData
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// copyValues copies values and the slices and maps in them, so a stored
+// session is not changed by the requests which loaded it, e.g. by AddFlash
+// appending to a shared array.
+func copyValues(values map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		result[k] = copyValue(v)
+	}
+	return result
+}
+
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		if v == nil {
+			return v
+		}
+		result := make([]interface{}, len(v))
+		for i := range v {
+			result[i] = copyValue(v[i])
+		}
+		return result
+	case map[string]interface{}:
+		if v == nil {
+			return v
+		}
+		return copyValues(v)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice:
+		if rv.IsNil() {
+			return v
+		}
+		result := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		reflect.Copy(result, rv)
+		return result.Interface()
+	case reflect.Map:
+		if rv.IsNil() {
+			return v
+		}
+		result := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			result.SetMapIndex(iter.Key(), iter.Value())
+		}
+		return result.Interface()
+	}
+	return v
+}
+
+// MemoryStore keeps sessions in memory. Sessions are lost on restart and not
+// shared between processes, so it suits development and single instances.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]memorySession
+	lastGC   time.Time
+}
+
+type memorySession struct {
+	values  map[string]interface{}
+	expires time.Time
+}
+
+var _ SessionStore = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an in-memory SessionStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]memorySession)}
+}
+
+func (m *MemoryStore) Load(token string) (string, map[string]interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[token]
+	if !ok || time.Now().After(s.expires) {
+		delete(m.sessions, token)
+		return "", nil, ErrSessionNotFound
+	}
+	return token, copyValues(s.values), nil
+}
+
+func (m *MemoryStore) Save(id string, values map[string]interface{}, ttl time.Duration) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sessions[id] = memorySession{values: copyValues(values), expires: now.Add(ttl)}
+
+	// drop expired sessions at most once a minute
+	if now.Sub(m.lastGC) > time.Minute {
+		m.lastGC = now
+		for k, s := range m.sessions {
+			if now.After(s.expires) {
+				delete(m.sessions, k)
+			}
+		}
+	}
+	return id, nil
+}
+
+func (m *MemoryStore) Delete(id string) error {
+	m.mu.Lock()
+	delete(m.sessions, id)
+	m.mu.Unlock()
+	return nil
+}
+
+// reSessionID matches the IDs made by newSessionID, so tokens from clients
+// can be used as file names.
+var reSessionID = regexp.MustCompile(`^[A-Za-z0-9_-]{16,128}$`)
+
+// FileStore keeps one gob encoded file per session in a directory.
+// The modification time of a file is the expiry of its session, so expired
+// files are found without reading them.
+type FileStore struct {
+	dir    string
+	mu     sync.Mutex
+	lastGC time.Time
+}
+
+var _ SessionStore = (*FileStore)(nil)
+
+// NewFileStore creates a SessionStore which saves sessions in dir.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (f *FileStore) file(id string) (string, error) {
+	if !reSessionID.MatchString(id) {
+		return "", ErrSessionNotFound
+	}
+	return filepath.Join(f.dir, "session_"+id), nil
+}
+
+func (f *FileStore) Load(token string) (string, map[string]interface{}, error) {
+	name, err := f.file(token)
+	if err != nil {
+		return "", nil, err
+	}
+	b, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return "", nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	data, err := decodeSession(b)
+	if err != nil {
+		return "", nil, err
+	}
+	if time.Now().After(data.Expires) {
+		_ = os.Remove(name)
+		return "", nil, ErrSessionNotFound
+	}
+	return token, data.Values, nil
+}
+
+func (f *FileStore) Save(id string, values map[string]interface{}, ttl time.Duration) (string, error) {
+	name, err := f.file(id)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	expires := now.Add(ttl)
+	b, err := encodeSession(&sessionData{ID: id, Values: values, Expires: expires})
+	if err != nil {
+		return "", err
+	}
+
+	// write to a temp file first, so readers never see a partial session
+	tmp, err := ioutil.TempFile(f.dir, "tmp_session_")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Chtimes(tmp.Name(), now, expires); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+
+	// drop expired sessions at most once a minute
+	f.mu.Lock()
+	if now.Sub(f.lastGC) > time.Minute {
+		f.lastGC = now
+		go f.removeExpired(now)
+	}
+	f.mu.Unlock()
+	return id, nil
+}
+
+// removeExpired deletes the session files which expired before now and the
+// temp files left behind by failed saves.
+func (f *FileStore) removeExpired(now time.Time) {
+	files, err := ioutil.ReadDir(f.dir)
+	if err != nil {
+		return
+	}
+	for _, fi := range files {
+		name := fi.Name()
+		switch {
+		case strings.HasPrefix(name, "session_") && now.After(fi.ModTime()):
+		case strings.HasPrefix(name, "tmp_session_") && now.Sub(fi.ModTime()) > time.Hour:
+		default:
+			continue
+		}
+		_ = os.Remove(filepath.Join(f.dir, name))
+	}
+}
+
+func (f *FileStore) Delete(id string) error {
+	name, err := f.file(id)
+	if err != nil {
+		return nil
+	}
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// maxSessionCookieSize keeps CookieStore tokens below the 4KB browsers accept.
+const maxSessionCookieSize = 4000
+
+// sessionCookieName is authenticated along with the CookieStore values.
+const sessionCookieName = "slimgo_session"
+
+// CookieStore keeps the session values encrypted in the session cookie
+// itself, so no server-side storage is needed. Delete can not revoke a
+// session, it only ends when the cookie is removed or expires.
+type CookieStore struct {
+	secrets []string
+}
+
+var _ SessionStore = (*CookieStore)(nil)
+
+// NewCookieStore creates a SessionStore which encrypts the values with
+// AES-GCM. The newest secret comes first and encrypts, all of them decrypt.
+func NewCookieStore(secrets ...string) *CookieStore {
+	return &CookieStore{secrets: secrets}
+}
+
+func (cs *CookieStore) Load(token string) (string, map[string]interface{}, error) {
+	plain, ok := decryptCookie(cs.secrets, sessionCookieName, token)
+	if !ok {
+		return "", nil, ErrSessionNotFound
+	}
+	data, err := decodeSession([]byte(plain))
+	if err != nil {
+		return "", nil, ErrSessionNotFound
+	}
+	return data.ID, data.Values, nil
+}
+
+func (cs *CookieStore) Save(id string, values map[string]interface{}, ttl time.Duration) (string, error) {
+	if len(cs.secrets) == 0 || cs.secrets[0] == "" {
+		return "", errors.New("cookie store needs a secret")
+	}
+	expires := time.Now().Add(ttl)
+	b, err := encodeSession(&sessionData{ID: id, Values: values, Expires: expires})
+	if err != nil {
+		return "", err
+	}
+	token, err := encryptCookie(cs.secrets[0], sessionCookieName, string(b), expires.Unix())
+	if err != nil {
+		return "", err
+	}
+	if len(token) > maxSessionCookieSize {
+		return "", fmt.Errorf("session is too large for a cookie (%d bytes)", len(token))
+	}
+	return token, nil
+}
+
+func (cs *CookieStore) Delete(id string) error {
+	return nil
+}
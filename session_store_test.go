@@ -0,0 +1,139 @@
+package slimgo
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileStoreRemoveExpired(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slimgo_sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const live, expired = "live0123456789abcdef", "expired0123456789abc"
+	if _, err := store.Save(live, map[string]interface{}{}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Save(expired, map[string]interface{}{}, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(dir, "tmp_session_1")
+	if err := ioutil.WriteFile(stale, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	_ = os.Chtimes(stale, old, old)
+
+	store.removeExpired(time.Now().Add(time.Minute))
+
+	for name, want := range map[string]bool{"session_" + live: true, "session_" + expired: false, "tmp_session_1": false} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if exists := err == nil; exists != want {
+			t.Errorf("%s exists = %v, want %v", name, exists, want)
+		}
+	}
+}
+
+func TestSessionTouchInterval(t *testing.T) {
+	tests := []struct {
+		idle, want time.Duration
+	}{
+		{30 * time.Minute, time.Minute},
+		{4 * time.Minute, time.Minute},
+		{20 * time.Second, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := touchInterval(tt.idle); got != tt.want {
+			t.Errorf("touchInterval(%v) = %v, want %v", tt.idle, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryStoreCopiesSlices(t *testing.T) {
+	store := NewMemoryStore()
+	flashes := make([]interface{}, 1, 4)
+	flashes[0] = "a"
+	tags := make([]string, 1, 4)
+	tags[0] = "t"
+	values := map[string]interface{}{sessionFlashesKey: flashes, "tags": tags}
+	if _, err := store.Save("sid", values, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	flashes = append(flashes, "changed after save")
+
+	_, first, _ := store.Load("sid")
+	_, second, _ := store.Load("sid")
+	f1 := append(first[sessionFlashesKey].([]interface{}), "first")
+	f2 := append(second[sessionFlashesKey].([]interface{}), "second")
+	t1 := append(first["tags"].([]string), "first")
+	t2 := append(second["tags"].([]string), "second")
+	if f1[1] != "first" || f2[1] != "second" || t1[1] != "first" || t2[1] != "second" {
+		t.Errorf("loaded sessions share arrays: %v %v %v %v", f1, f2, t1, t2)
+	}
+
+	_, third, _ := store.Load("sid")
+	if got := third[sessionFlashesKey].([]interface{}); len(got) != 1 || got[0] != "a" {
+		t.Errorf("stored flashes = %v", got)
+	}
+}
+
+// TestMemoryStoreConcurrentFlashes is meant for go test -race: concurrent
+// requests of one session append flashes to their own copies.
+func TestMemoryStoreConcurrentFlashes(t *testing.T) {
+	const concurrent = 4
+	var (
+		setup    = true
+		appended sync.WaitGroup
+	)
+	s := New()
+	s.SetMode(Release)
+	s.UseSessions(NewMemoryStore())
+	s.GET("/flash", func(c Context) {
+		_ = c.Flash("info", "hello")
+		if !setup {
+			// all requests append before any of them saves
+			appended.Done()
+			appended.Wait()
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	get := func(cookies []*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/flash", nil)
+		for _, ck := range cookies {
+			req.AddCookie(ck)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+
+	// three flashes leave spare capacity in a slice grown by append
+	cookies := get(nil).Result().Cookies()
+	get(cookies)
+	get(cookies)
+
+	setup = false
+	appended.Add(concurrent)
+	var done sync.WaitGroup
+	for i := 0; i < concurrent; i++ {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			get(cookies)
+		}()
+	}
+	done.Wait()
+}
@@ -2,12 +2,16 @@ package slimgo
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"path"
 	"runtime"
 	"runtime/debug"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/gitwillsky/slimgo/binding"
 )
 
 const Version = "slimgo v1.0.0"
@@ -19,8 +23,22 @@ type Server struct {
 	router     *Router
 	mode       string
 	logger     Logger
+	slogger    StructuredLogger
 	middleware []Handler
 	lock       sync.Locker
+
+	maxBodySize int64
+	jsonOptions *binding.JSONOptions
+
+	secureJSONPrefix string
+	cookieSecrets    []string
+	cookieDefaults   CookieOptions
+	sessionCookieTTL time.Duration
+
+	sessionStore   SessionStore
+	sessionOptions SessionOptions
+
+	trustedProxies []*net.IPNet
 }
 
 // New create new server handler
@@ -36,8 +54,11 @@ func New() *Server {
 		logger: &logger{
 			debug: true,
 		},
-		mode: Debug,
+		mode:             Debug,
+		sessionCookieTTL: defaultSessionCookieTTL,
 	}
+	s.slogger = FromLogger(s.logger)
+	s.trustedProxies, _ = parseCIDRs(defaultTrustedProxies)
 
 	fmt.Printf(banner, Version, runtime.Version())
 	return s
@@ -48,10 +69,42 @@ func (s *Server) SetMode(mode string) {
 	s.logger = &logger{
 		debug: mode == Debug,
 	}
+	s.slogger = FromLogger(s.logger)
 }
 
 func (s *Server) SetLogger(l Logger) {
 	s.logger = l
+	s.slogger = FromLogger(l)
+}
+
+// SetStructuredLogger sets a leveled logger with fields, e.g. NewJSONLogger.
+// The server's own printf-style lines are written to it as well.
+func (s *Server) SetStructuredLogger(l StructuredLogger) {
+	s.slogger = l
+	s.logger = ToLogger(l)
+}
+
+// Logger returns the structured logger of the server.
+func (s *Server) Logger() StructuredLogger {
+	return s.slogger
+}
+
+// SetMaxBodySize limits request bodies to n bytes, 0 means no limit.
+// Routes and groups can set a lower limit with the MaxBodySize middleware.
+func (s *Server) SetMaxBodySize(n int64) {
+	s.maxBodySize = n
+}
+
+// SetJSONOptions sets the decoder options of the JSON binding used by Context.Bind.
+// Routes and groups can override them with the JSONDecoderOptions middleware.
+func (s *Server) SetJSONOptions(opts binding.JSONOptions) {
+	s.jsonOptions = &opts
+}
+
+// SetSecureJSONPrefix sets the prefix written by Context.SecureJSON,
+// "while(1);" by default.
+func (s *Server) SetSecureJSONPrefix(prefix string) {
+	s.secureJSONPrefix = prefix
 }
 
 func (s *Server) Start(addr string) error {
@@ -231,13 +284,22 @@ func (g *groupRoutes) DELETE(relativePath string, handlers ...Handler) *groupRou
 
 // implement ServeHTTP
 func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var c *context
 	defer func() {
 		// default panic handler
 		if err := recover(); err != nil {
+			var requestID string
+			if c != nil {
+				requestID = c.requestID()
+			}
 			// now this is raw response can not use compress
 			w.Header().Del("Content-Encoding")
-			defaultPanicHandler(w, req, err, debug.Stack())
-			s.logger.Errorf("%s", debug.Stack())
+			defaultPanicHandler(w, req, err, debug.Stack(), requestID)
+			if requestID != "" {
+				s.logger.Errorf("[%s] %s", requestID, debug.Stack())
+			} else {
+				s.logger.Errorf("%s", debug.Stack())
+			}
 		}
 	}()
 
@@ -250,8 +312,12 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	c := newContext()
+	c = newContext()
 	c.init(s, w, req, s.middleware...)
 	c.run()
+	// handlers which wrote nothing still need their session and flashes saved
+	if rw, ok := c.response.(*responseWriter); ok {
+		rw.runBeforeWrite()
+	}
 	c.recycle()
 }
@@ -0,0 +1,257 @@
+package slimgo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log line.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return "level(" + strconv.Itoa(int(l)) + ")"
+}
+
+// Field is a key/value pair of a structured log line.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F creates a Field.
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// StructuredLogger writes leveled log lines with key/value fields.
+type StructuredLogger interface {
+	Debug(msg string, fields ...Field)
+	Info(msg string, fields ...Field)
+	Warn(msg string, fields ...Field)
+	Error(msg string, fields ...Field)
+	// With returns a logger which adds fields to every line.
+	With(fields ...Field) StructuredLogger
+}
+
+// jsonLogger writes one JSON object per line.
+type jsonLogger struct {
+	out    *lockedWriter
+	level  Level
+	fields []Field
+}
+
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLogger creates a StructuredLogger which writes lines like
+// {"time":"...","level":"info","msg":"...","key":"value"} to w, skipping
+// lines below level.
+func NewJSONLogger(w io.Writer, level Level) StructuredLogger {
+	return &jsonLogger{out: &lockedWriter{w: w}, level: level}
+}
+
+func (l *jsonLogger) Debug(msg string, fields ...Field) { l.log(LevelDebug, msg, fields) }
+func (l *jsonLogger) Info(msg string, fields ...Field)  { l.log(LevelInfo, msg, fields) }
+func (l *jsonLogger) Warn(msg string, fields ...Field)  { l.log(LevelWarn, msg, fields) }
+func (l *jsonLogger) Error(msg string, fields ...Field) { l.log(LevelError, msg, fields) }
+
+func (l *jsonLogger) With(fields ...Field) StructuredLogger {
+	return &jsonLogger{out: l.out, level: l.level, fields: appendFields(l.fields, fields)}
+}
+
+func (l *jsonLogger) log(level Level, msg string, fields []Field) {
+	if level < l.level {
+		return
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	buf.WriteString(`{"time":`)
+	writeJSONValue(buf, time.Now().Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJSONValue(buf, level.String())
+	buf.WriteString(`,"msg":`)
+	writeJSONValue(buf, msg)
+	for _, fs := range [][]Field{l.fields, fields} {
+		for _, f := range fs {
+			buf.WriteByte(',')
+			writeJSONValue(buf, f.Key)
+			buf.WriteByte(':')
+			writeJSONValue(buf, f.Value)
+		}
+	}
+	buf.WriteString("}\n")
+
+	l.out.mu.Lock()
+	_, _ = l.out.w.Write(buf.Bytes())
+	l.out.mu.Unlock()
+}
+
+func writeJSONValue(buf *bytes.Buffer, v interface{}) {
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(b)
+}
+
+// appendFields returns a new slice, so loggers derived by With never share
+// their fields.
+func appendFields(a, b []Field) []Field {
+	fields := make([]Field, 0, len(a)+len(b))
+	return append(append(fields, a...), b...)
+}
+
+// printfLogger adapts a printf-style Logger to StructuredLogger.
+type printfLogger struct {
+	l      Logger
+	fields []Field
+}
+
+// FromLogger adapts a printf-style Logger, as set by Server.SetLogger, to
+// StructuredLogger. Fields are appended to the message as key=value. Warn
+// lines use the Warnf method of l if it has one, Infof otherwise.
+func FromLogger(l Logger) StructuredLogger {
+	if sl, ok := l.(*structuredPrintf); ok {
+		return sl.sl
+	}
+	return &printfLogger{l: l}
+}
+
+func (p *printfLogger) Debug(msg string, fields ...Field) {
+	p.l.Debugf("%s", p.format(msg, fields))
+}
+
+func (p *printfLogger) Info(msg string, fields ...Field) {
+	p.l.Infof("%s", p.format(msg, fields))
+}
+
+func (p *printfLogger) Warn(msg string, fields ...Field) {
+	if w, ok := p.l.(interface {
+		Warnf(format string, fields ...interface{})
+	}); ok {
+		w.Warnf("%s", p.format(msg, fields))
+		return
+	}
+	p.l.Infof("%s", p.format(msg, fields))
+}
+
+func (p *printfLogger) Error(msg string, fields ...Field) {
+	p.l.Errorf("%s", p.format(msg, fields))
+}
+
+func (p *printfLogger) With(fields ...Field) StructuredLogger {
+	return &printfLogger{l: p.l, fields: appendFields(p.fields, fields)}
+}
+
+// format renders msg and the fields in logfmt style.
+func (p *printfLogger) format(msg string, fields []Field) string {
+	if len(p.fields) == 0 && len(fields) == 0 {
+		return msg
+	}
+	var sb strings.Builder
+	sb.WriteString(msg)
+	for _, fs := range [][]Field{p.fields, fields} {
+		for _, f := range fs {
+			sb.WriteByte(' ')
+			sb.WriteString(f.Key)
+			sb.WriteByte('=')
+			v := fmt.Sprint(f.Value)
+			if v == "" || strings.ContainsAny(v, " \t\r\n\"=") {
+				v = strconv.Quote(v)
+			}
+			sb.WriteString(v)
+		}
+	}
+	return sb.String()
+}
+
+// structuredPrintf adapts a StructuredLogger to the printf-style Logger.
+type structuredPrintf struct {
+	sl StructuredLogger
+}
+
+// ToLogger adapts a StructuredLogger to the printf-style Logger, for code
+// which still logs with Debugf, Infof and Errorf.
+func ToLogger(sl StructuredLogger) Logger {
+	if p, ok := sl.(*printfLogger); ok && len(p.fields) == 0 {
+		return p.l
+	}
+	return &structuredPrintf{sl: sl}
+}
+
+func (s *structuredPrintf) Debugf(format string, fields ...interface{}) {
+	s.sl.Debug(fmt.Sprintf(format, fields...))
+}
+
+func (s *structuredPrintf) Infof(format string, fields ...interface{}) {
+	s.sl.Info(fmt.Sprintf(format, fields...))
+}
+
+func (s *structuredPrintf) Warnf(format string, fields ...interface{}) {
+	s.sl.Warn(fmt.Sprintf(format, fields...))
+}
+
+func (s *structuredPrintf) Errorf(format string, fields ...interface{}) {
+	s.sl.Error(fmt.Sprintf(format, fields...))
+}
+
+// Logger returns the server's structured logger with the request ID, method,
+// route pattern, client IP and the fields added by AddLogFields, so all lines
+// of a request can be correlated.
+func (c *context) Logger() StructuredLogger {
+	if c.log != nil {
+		return c.log
+	}
+
+	fields := make([]Field, 0, 4+len(c.logFields))
+	if requestID := c.requestID(); requestID != "" {
+		fields = append(fields, F("request_id", requestID))
+	}
+	fields = append(fields, F("method", c.request.Method))
+	if c.regPath != "" {
+		fields = append(fields, F("route", c.regPath))
+	} else {
+		fields = append(fields, F("path", c.request.URL.Path))
+	}
+	fields = append(fields, F("client_ip", c.ClientIP()))
+	fields = append(fields, c.logFields...)
+
+	c.log = c.server.slogger.With(fields...)
+	return c.log
+}
+
+// AddLogFields adds fields to the lines of Logger, e.g. the user ID set by an
+// authentication middleware.
+func (c *context) AddLogFields(fields ...Field) {
+	c.logFields = append(c.logFields, fields...)
+	c.log = nil
+}
@@ -0,0 +1,261 @@
+package slimgo
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// decodeLogLines decodes the JSON lines written by a jsonLogger.
+func decodeLogLines(t *testing.T, out string) []map[string]interface{} {
+	t.Helper()
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]interface{}
+		if err := stdjson.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestJSONLogger(t *testing.T) {
+	var buf bytes.Buffer
+	base := NewJSONLogger(&buf, LevelDebug)
+	l := base.With(F("app", "slimgo"))
+	l.Info("hello \"world\"\n", F("n", 42), F("err", errors.New("boom")), F("ok", true), F("ch", make(chan int)))
+
+	lines := decodeLogLines(t, buf.String())
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1", len(lines))
+	}
+	line := lines[0]
+	want := map[string]interface{}{
+		"level": "info",
+		"msg":   "hello \"world\"\n",
+		"app":   "slimgo",
+		"n":     float64(42),
+		"err":   "boom",
+		"ok":    true,
+	}
+	for k, v := range want {
+		if line[k] != v {
+			t.Errorf("%s = %#v, want %#v", k, line[k], v)
+		}
+	}
+	if _, ok := line["time"].(string); !ok {
+		t.Error("missing time")
+	}
+	if s, ok := line["ch"].(string); !ok || s == "" {
+		t.Errorf("unencodable value = %#v, want its fmt string", line["ch"])
+	}
+}
+
+func TestJSONLoggerLevels(t *testing.T) {
+	tests := []struct {
+		level Level
+		want  []string
+	}{
+		{LevelDebug, []string{"debug", "info", "warn", "error"}},
+		{LevelInfo, []string{"info", "warn", "error"}},
+		{LevelWarn, []string{"warn", "error"}},
+		{LevelError, []string{"error"}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		l := NewJSONLogger(&buf, tt.level)
+		l.Debug("m")
+		l.Info("m")
+		l.Warn("m")
+		l.Error("m")
+
+		var got []string
+		for _, line := range decodeLogLines(t, buf.String()) {
+			got = append(got, line["level"].(string))
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("level %s: wrote %v, want %v", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestJSONLoggerWithDoesNotShareFields(t *testing.T) {
+	var buf bytes.Buffer
+	parent := NewJSONLogger(&buf, LevelDebug).With(F("a", 1))
+	left := parent.With(F("b", 2))
+	right := parent.With(F("c", 3))
+	left.Info("left")
+	right.Info("right")
+	parent.Info("parent")
+
+	lines := decodeLogLines(t, buf.String())
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines", len(lines))
+	}
+	for i, keys := range []struct{ has, not string }{{"b", "c"}, {"c", "b"}, {"a", "b"}} {
+		if _, ok := lines[i][keys.has]; !ok {
+			t.Errorf("line %d misses %s: %v", i, keys.has, lines[i])
+		}
+		if _, ok := lines[i][keys.not]; ok {
+			t.Errorf("line %d has %s: %v", i, keys.not, lines[i])
+		}
+	}
+}
+
+// recordLogger is a printf-style Logger which records its lines.
+type recordLogger struct {
+	lines []string
+}
+
+func (r *recordLogger) Debugf(format string, args ...interface{}) {
+	r.lines = append(r.lines, "D "+fmt.Sprintf(format, args...))
+}
+
+func (r *recordLogger) Infof(format string, args ...interface{}) {
+	r.lines = append(r.lines, "I "+fmt.Sprintf(format, args...))
+}
+
+func (r *recordLogger) Errorf(format string, args ...interface{}) {
+	r.lines = append(r.lines, "E "+fmt.Sprintf(format, args...))
+}
+
+// warnRecordLogger also has Warnf.
+type warnRecordLogger struct {
+	recordLogger
+}
+
+func (r *warnRecordLogger) Warnf(format string, args ...interface{}) {
+	r.lines = append(r.lines, "W "+fmt.Sprintf(format, args...))
+}
+
+func TestFromLogger(t *testing.T) {
+	rec := &recordLogger{}
+	l := FromLogger(rec).With(F("a", 1))
+	l.Debug("d")
+	l.Info("i", F("b", "x y"), F("c", ""))
+	l.Warn("w")
+	l.Error("e", F("err", errors.New("boom")))
+	want := []string{`D d a=1`, `I i a=1 b="x y" c=""`, `I w a=1`, `E e a=1 err=boom`}
+	if strings.Join(rec.lines, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", rec.lines, want)
+	}
+
+	wrec := &warnRecordLogger{}
+	FromLogger(wrec).Warn("w", F("k", "v"))
+	if len(wrec.lines) != 1 || wrec.lines[0] != "W w k=v" {
+		t.Errorf("Warn with Warnf: %q", wrec.lines)
+	}
+}
+
+func TestLoggerAdapters(t *testing.T) {
+	rec := &recordLogger{}
+	if ToLogger(FromLogger(rec)) != Logger(rec) {
+		t.Error("ToLogger(FromLogger(l)) is not l")
+	}
+
+	var buf bytes.Buffer
+	jl := NewJSONLogger(&buf, LevelInfo)
+	if FromLogger(ToLogger(jl)) != jl {
+		t.Error("FromLogger(ToLogger(l)) is not l")
+	}
+
+	pl := ToLogger(jl)
+	pl.Debugf("hidden %d", 0)
+	pl.Infof("count %d", 1)
+	pl.Errorf("failed: %s", "x")
+	lines := decodeLogLines(t, buf.String())
+	if len(lines) != 2 || lines[0]["msg"] != "count 1" || lines[1]["level"] != "error" || lines[1]["msg"] != "failed: x" {
+		t.Errorf("got %v", lines)
+	}
+}
+
+func TestContextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	s := New()
+	s.SetMode(Release)
+	s.SetStructuredLogger(NewJSONLogger(&buf, LevelDebug))
+	s.Use(
+		func(c Context) {
+			c.Logger().Info("before request id")
+		},
+		RequestID(RequestIDOptions{}),
+		func(c Context) {
+			c.Logger().Info("before fields")
+			c.AddLogFields(F("user", "u1"))
+		},
+	)
+	s.GET("/items/:id", func(c Context) {
+		c.Logger().Info("handled")
+		c.AddLogFields(F("item", c.Param("id")))
+		c.Logger().Info("handled again")
+		c.String(http.StatusOK, "ok")
+	})
+	s.GET("/plain", func(c Context) {
+		c.Logger().Info("plain")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/items/7", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	requestID := w.Header().Get(RequestIDHeader)
+	if requestID == "" {
+		t.Fatal("no request ID")
+	}
+
+	lines := decodeLogLines(t, buf.String())
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines: %s", len(lines), buf.String())
+	}
+	tests := []struct {
+		msg    string
+		fields map[string]interface{}
+		absent []string
+	}{
+		{"before request id", map[string]interface{}{"method": "GET", "route": "/items/:id", "client_ip": "198.51.100.1"}, []string{"request_id", "user"}},
+		{"before fields", map[string]interface{}{"request_id": requestID}, []string{"user"}},
+		{"handled", map[string]interface{}{"request_id": requestID, "user": "u1"}, []string{"item"}},
+		{"handled again", map[string]interface{}{"request_id": requestID, "user": "u1", "item": "7"}, nil},
+	}
+	for i, tt := range tests {
+		line := lines[i]
+		if line["msg"] != tt.msg {
+			t.Errorf("line %d: msg %q, want %q", i, line["msg"], tt.msg)
+			continue
+		}
+		for k, v := range tt.fields {
+			if line[k] != v {
+				t.Errorf("%s: %s = %#v, want %#v", tt.msg, k, line[k], v)
+			}
+		}
+		for _, k := range tt.absent {
+			if _, ok := line[k]; ok {
+				t.Errorf("%s: unexpected %s = %#v", tt.msg, k, line[k])
+			}
+		}
+	}
+
+	// the logger cached by the recycled context does not leak into the next request
+	buf.Reset()
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/plain", nil))
+	for _, line := range decodeLogLines(t, buf.String()) {
+		if line["msg"] == "plain" {
+			if line["user"] != "u1" || line["request_id"] == requestID || line["route"] != "/plain" {
+				t.Errorf("plain: %v", line)
+			}
+			if _, ok := line["item"]; ok {
+				t.Errorf("plain has item of previous request: %v", line)
+			}
+		}
+	}
+}
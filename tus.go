@@ -0,0 +1,532 @@
+package slimgo
+
+import (
+	"bytes"
+	stdctx "context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TusVersion is the version of the tus resumable upload protocol served by TusHandler.
+const TusVersion = "1.0.0"
+
+const (
+	tusContentType = "application/offset+octet-stream"
+	tusExtensions  = "creation,creation-with-upload,expiration,checksum,termination"
+	tusChecksums   = "md5,sha1,sha256"
+	// StatusChecksumMismatch is the tus status of a chunk with a wrong checksum.
+	StatusChecksumMismatch = 460
+)
+
+var reTusID = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// errChecksumMismatch is returned by checksumReader at the end of a bad chunk.
+var errChecksumMismatch = errors.New("tus: checksum mismatch")
+
+// TusOptions configures a TusHandler.
+type TusOptions struct {
+	// Storage keeps the uploads, required.
+	Storage UploadAppender
+	// Dir is the folder of the uploads in Storage, "tus" by default.
+	Dir string
+	// MaxSize is the limit of an upload in bytes, 0 means no limit.
+	MaxSize int64
+	// Expiration removes unfinished uploads this long after their creation,
+	// 24 hours by default. Expired uploads are removed when they are accessed
+	// and by TusHandler.Cleanup, which should run periodically.
+	Expiration time.Duration
+	// OnComplete is called when the last chunk of an upload was received,
+	// or when an upload of length 0 is created.
+	OnComplete func(c Context, upload *TusUpload)
+}
+
+// TusUpload describes a resumable upload.
+type TusUpload struct {
+	ID string
+	// Name is the name of the data in the storage.
+	Name     string
+	Length   int64
+	Metadata map[string]string
+	Expires  time.Time
+	// Offset is the number of bytes received so far.
+	Offset int64
+
+	// rawMetadata is the Upload-Metadata header of the creation request.
+	rawMetadata string
+}
+
+// tusInfo is stored next to the data of an upload.
+type tusInfo struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Length   int64     `json:"length"`
+	Metadata string    `json:"metadata"`
+	Expires  time.Time `json:"expires"`
+}
+
+// TusHandler serves resumable uploads by the tus 1.0 protocol
+// (https://tus.io/protocols/resumable-upload.html) with the creation,
+// expiration, checksum and termination extensions.
+type TusHandler struct {
+	opts TusOptions
+
+	mu     sync.Mutex
+	locked map[string]bool
+}
+
+// NewTusHandler creates a TusHandler, mount it with Mount.
+func NewTusHandler(opts TusOptions) *TusHandler {
+	if opts.Storage == nil {
+		panic(errors.New("tus handler needs a storage"))
+	}
+	if opts.Dir == "" {
+		opts.Dir = "tus"
+	}
+	if opts.Expiration <= 0 {
+		opts.Expiration = 24 * time.Hour
+	}
+	return &TusHandler{opts: opts, locked: make(map[string]bool)}
+}
+
+// Mount registers the handler on the route group g: uploads are created by
+// POST to the group root and sent to the returned location.
+//
+//	tus := slimgo.NewTusHandler(slimgo.TusOptions{Storage: slimgo.NewLocalStorage("data")})
+//	tus.Mount(s.Root("/files", auth))
+func (t *TusHandler) Mount(g *groupRoutes) {
+	root := CleanURLPath("/" + g.rootPath)
+	g.Server.Register("OPTIONS", root, g.combineHandlers(t.options)...)
+	g.Server.Register("POST", root, g.combineHandlers(t.create)...)
+	g.OPTIONS("/:id", t.options)
+	g.HEAD("/:id", t.head)
+	g.PATCH("/:id", t.patch)
+	g.DELETE("/:id", t.delete)
+}
+
+func (t *TusHandler) options(c Context) {
+	h := c.ResponseHeader()
+	h.Set("Tus-Resumable", TusVersion)
+	h.Set("Tus-Version", TusVersion)
+	h.Set("Tus-Extension", tusExtensions)
+	h.Set("Tus-Checksum-Algorithm", tusChecksums)
+	if t.opts.MaxSize > 0 {
+		h.Set("Tus-Max-Size", strconv.FormatInt(t.opts.MaxSize, 10))
+	}
+	c.WriteResponseHeader(http.StatusNoContent)
+}
+
+// checkVersion answers requests of other protocol versions with 412.
+func (t *TusHandler) checkVersion(c Context) bool {
+	c.ResponseHeader().Set("Tus-Resumable", TusVersion)
+	if c.Request().Header.Get("Tus-Resumable") != TusVersion {
+		c.ResponseHeader().Set("Tus-Version", TusVersion)
+		c.WriteResponseHeader(http.StatusPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+func (t *TusHandler) create(c Context) {
+	if !t.checkVersion(c) {
+		return
+	}
+	req := c.Request()
+
+	length, err := strconv.ParseInt(req.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		c.String(http.StatusBadRequest, "invalid Upload-Length")
+		return
+	}
+	if t.opts.MaxSize > 0 && length > t.opts.MaxSize {
+		c.String(http.StatusRequestEntityTooLarge, http.StatusText(http.StatusRequestEntityTooLarge))
+		return
+	}
+	metadata, ok := parseTusMetadata(req.Header.Get("Upload-Metadata"))
+	if !ok {
+		c.String(http.StatusBadRequest, "invalid Upload-Metadata")
+		return
+	}
+
+	id := randomFilename()
+	upload := &TusUpload{
+		ID:          id,
+		Name:        path.Join(t.opts.Dir, id),
+		Length:      length,
+		Metadata:    metadata,
+		Expires:     time.Now().Add(t.opts.Expiration).UTC(),
+		rawMetadata: req.Header.Get("Upload-Metadata"),
+	}
+	info, err := json.Marshal(&tusInfo{
+		ID:       upload.ID,
+		Name:     upload.Name,
+		Length:   upload.Length,
+		Metadata: upload.rawMetadata,
+		Expires:  upload.Expires,
+	})
+	if err == nil {
+		_, err = t.opts.Storage.Save(upload.Name, bytes.NewReader(nil))
+	}
+	if err == nil {
+		_, err = t.opts.Storage.Save(upload.Name+".info", bytes.NewReader(info))
+	}
+	if err != nil {
+		_ = t.opts.Storage.Delete(upload.Name)
+		t.fail(c, err)
+		return
+	}
+
+	h := c.ResponseHeader()
+	h.Set("Location", strings.TrimSuffix(req.URL.Path, "/")+"/"+id)
+	h.Set("Upload-Expires", upload.Expires.Format(http.TimeFormat))
+
+	// creation-with-upload: the first chunk may come with the request
+	if req.ContentLength != 0 && req.Header.Get("Content-Type") == tusContentType {
+		t.lock(id)
+		defer t.unlock(id)
+		status := t.write(c, upload)
+		if status != 0 {
+			c.String(status, tusStatusText(status))
+			return
+		}
+		h.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	} else if upload.Length == 0 && t.opts.OnComplete != nil {
+		// an empty upload is complete once it exists
+		t.opts.OnComplete(c, upload)
+	}
+	c.WriteResponseHeader(http.StatusCreated)
+}
+
+func (t *TusHandler) head(c Context) {
+	if !t.checkVersion(c) {
+		return
+	}
+	upload, status := t.load(c.Param("id"))
+	if status != 0 {
+		c.WriteResponseHeader(status)
+		return
+	}
+
+	h := c.ResponseHeader()
+	h.Set("Cache-Control", "no-store")
+	h.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	h.Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	h.Set("Upload-Expires", upload.Expires.Format(http.TimeFormat))
+	if upload.rawMetadata != "" {
+		h.Set("Upload-Metadata", upload.rawMetadata)
+	}
+	c.WriteResponseHeader(http.StatusOK)
+}
+
+func (t *TusHandler) patch(c Context) {
+	if !t.checkVersion(c) {
+		return
+	}
+	req := c.Request()
+	if req.Header.Get("Content-Type") != tusContentType {
+		c.String(http.StatusUnsupportedMediaType, "Content-Type must be "+tusContentType)
+		return
+	}
+
+	id := c.Param("id")
+	if !t.lock(id) {
+		c.String(http.StatusLocked, "upload is in use")
+		return
+	}
+	defer t.unlock(id)
+
+	upload, status := t.load(id)
+	if status != 0 {
+		c.WriteResponseHeader(status)
+		return
+	}
+	offset, err := strconv.ParseInt(req.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset != upload.Offset {
+		c.String(http.StatusConflict, "Upload-Offset does not match")
+		return
+	}
+
+	if status := t.write(c, upload); status != 0 {
+		c.String(status, tusStatusText(status))
+		return
+	}
+	c.ResponseHeader().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.ResponseHeader().Set("Upload-Expires", upload.Expires.Format(http.TimeFormat))
+	c.WriteResponseHeader(http.StatusNoContent)
+}
+
+// write appends the request body to upload and returns the error status, or
+// 0 on success. Without a checksum the bytes received before a broken
+// connection are kept, so the client can resume from there.
+func (t *TusHandler) write(c Context, upload *TusUpload) int {
+	req := c.Request()
+	body := io.Reader(&limitedReader{r: req.Body, limit: upload.Length - upload.Offset})
+
+	checksum := req.Header.Get("Upload-Checksum")
+	if checksum != "" {
+		cr, ok := newChecksumReader(body, checksum)
+		if !ok {
+			return http.StatusBadRequest
+		}
+		body = cr
+	}
+
+	n, err := t.opts.Storage.Append(upload.Name, body)
+	if err != nil {
+		tooLarge := errors.Is(err, ErrUploadTooLarge) || errors.Is(err, ErrBodyTooLarge)
+		if checksum == "" && !tooLarge {
+			upload.Offset += n
+			c.ResponseHeader().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+			c.Logger().Error("tus: write failed", F("upload", upload.ID), F("error", err))
+			return http.StatusInternalServerError
+		}
+
+		// drop the whole chunk
+		if err := t.opts.Storage.Truncate(upload.Name, upload.Offset); err != nil {
+			c.Logger().Error("tus: truncate failed", F("upload", upload.ID), F("error", err))
+		}
+		switch {
+		case errors.Is(err, errChecksumMismatch):
+			return StatusChecksumMismatch
+		case tooLarge:
+			return http.StatusRequestEntityTooLarge
+		}
+		return http.StatusInternalServerError
+	}
+
+	upload.Offset += n
+	if upload.Offset == upload.Length && t.opts.OnComplete != nil {
+		t.opts.OnComplete(c, upload)
+	}
+	return 0
+}
+
+func (t *TusHandler) delete(c Context) {
+	if !t.checkVersion(c) {
+		return
+	}
+	id := c.Param("id")
+	if !t.lock(id) {
+		c.String(http.StatusLocked, "upload is in use")
+		return
+	}
+	defer t.unlock(id)
+
+	upload, status := t.load(id)
+	if status != 0 {
+		c.WriteResponseHeader(status)
+		return
+	}
+	if err := t.remove(upload.Name); err != nil {
+		t.fail(c, err)
+		return
+	}
+	c.WriteResponseHeader(http.StatusNoContent)
+}
+
+// load reads the upload id with its offset. It returns 404 for unknown and
+// 410 for expired unfinished uploads, which are removed.
+func (t *TusHandler) load(id string) (*TusUpload, int) {
+	if !reTusID.MatchString(id) {
+		return nil, http.StatusNotFound
+	}
+	name := path.Join(t.opts.Dir, id)
+
+	r, err := t.opts.Storage.Open(name + ".info")
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, http.StatusNotFound
+		}
+		return nil, http.StatusInternalServerError
+	}
+	b, err := ioutil.ReadAll(r)
+	_ = r.Close()
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+
+	var info tusInfo
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, http.StatusInternalServerError
+	}
+	if info.ID != id || info.Name != name {
+		return nil, http.StatusNotFound
+	}
+
+	metadata, _ := parseTusMetadata(info.Metadata)
+	upload := TusUpload{
+		ID:          info.ID,
+		Name:        info.Name,
+		Length:      info.Length,
+		Metadata:    metadata,
+		Expires:     info.Expires,
+		rawMetadata: info.Metadata,
+	}
+	if upload.Offset, err = t.opts.Storage.Size(name); err != nil {
+		return nil, http.StatusInternalServerError
+	}
+	if upload.Offset < upload.Length && time.Now().After(info.Expires) {
+		_ = t.remove(name)
+		return nil, http.StatusGone
+	}
+	return &upload, 0
+}
+
+// Cleanup removes the unfinished uploads which have expired and returns how
+// many were removed. Uploads are otherwise only removed when a client comes
+// back to them, so run it periodically, e.g.
+//
+//	go func() {
+//		for range time.Tick(time.Hour) {
+//			if _, err := tus.Cleanup(context.Background()); err != nil {
+//				log.Print(err)
+//			}
+//		}
+//	}()
+//
+// The storage must implement UploadLister, as LocalStorage and MemoryStorage do.
+func (t *TusHandler) Cleanup(ctx stdctx.Context) (int, error) {
+	lister, ok := t.opts.Storage.(UploadLister)
+	if !ok {
+		return 0, errors.New("tus: cleanup needs a storage which implements UploadLister")
+	}
+	names, err := lister.List(t.opts.Dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var removed int
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+		id := strings.TrimSuffix(path.Base(name), ".info")
+		if !strings.HasSuffix(name, ".info") || !reTusID.MatchString(id) || !t.lock(id) {
+			continue
+		}
+		// load removes expired uploads
+		if _, status := t.load(id); status == http.StatusGone {
+			removed++
+		}
+		t.unlock(id)
+	}
+	return removed, nil
+}
+
+func (t *TusHandler) remove(name string) error {
+	if err := t.opts.Storage.Delete(name); err != nil {
+		return err
+	}
+	return t.opts.Storage.Delete(name + ".info")
+}
+
+// lock makes sure only one request writes to an upload at a time.
+func (t *TusHandler) lock(id string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.locked[id] {
+		return false
+	}
+	t.locked[id] = true
+	return true
+}
+
+func (t *TusHandler) unlock(id string) {
+	t.mu.Lock()
+	delete(t.locked, id)
+	t.mu.Unlock()
+}
+
+func (t *TusHandler) fail(c Context, err error) {
+	c.Logger().Error("tus: storage failed", F("error", err))
+	c.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+}
+
+func tusStatusText(status int) string {
+	if status == StatusChecksumMismatch {
+		return "Checksum Mismatch"
+	}
+	return http.StatusText(status)
+}
+
+// parseTusMetadata parses "key base64value,key2 base64value2". Values may be omitted.
+func parseTusMetadata(s string) (map[string]string, bool) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(s) == "" {
+		return metadata, true
+	}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.Fields(pair)
+		if len(kv) == 0 || len(kv) > 2 {
+			return nil, false
+		}
+		var value string
+		if len(kv) == 2 {
+			b, err := base64.StdEncoding.DecodeString(kv[1])
+			if err != nil {
+				return nil, false
+			}
+			value = string(b)
+		}
+		if _, ok := metadata[kv[0]]; ok {
+			return nil, false
+		}
+		metadata[kv[0]] = value
+	}
+	return metadata, true
+}
+
+// checksumReader fails with errChecksumMismatch at the end of the data when
+// its hash is not the expected one.
+type checksumReader struct {
+	r        io.Reader
+	h        hash.Hash
+	expected []byte
+}
+
+// newChecksumReader parses an Upload-Checksum header like "sha1 base64hash".
+func newChecksumReader(r io.Reader, header string) (*checksumReader, bool) {
+	parts := strings.Fields(header)
+	if len(parts) != 2 {
+		return nil, false
+	}
+	expected, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, false
+	}
+
+	var h hash.Hash
+	switch parts[0] {
+	case "md5":
+		h = md5.New()
+	case "sha1":
+		h = sha1.New()
+	case "sha256":
+		h = sha256.New()
+	default:
+		return nil, false
+	}
+	return &checksumReader{r: r, h: h, expected: expected}, true
+}
+
+func (cr *checksumReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.h.Write(p[:n])
+	if err == io.EOF && !bytes.Equal(cr.h.Sum(nil), cr.expected) {
+		return n, errChecksumMismatch
+	}
+	return n, err
+}
@@ -0,0 +1,194 @@
+package slimgo
+
+import (
+	"bytes"
+	stdctx "context"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTusCleanup(t *testing.T) {
+	storage := NewMemoryStorage()
+	tus := NewTusHandler(TusOptions{Storage: storage})
+
+	add := func(id string, length int64, data string, expires time.Time) {
+		name := path.Join("tus", id)
+		info, err := json.Marshal(&tusInfo{ID: id, Name: name, Length: length, Expires: expires})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = storage.Save(name, strings.NewReader(data))
+		_, _ = storage.Save(name+".info", bytes.NewReader(info))
+	}
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+	const (
+		expired  = "00000000000000000000000000000001"
+		active   = "00000000000000000000000000000002"
+		finished = "00000000000000000000000000000003"
+		busy     = "00000000000000000000000000000004"
+	)
+	add(expired, 10, "abc", past)
+	add(active, 10, "abc", future)
+	add(finished, 3, "abc", past)
+	add(busy, 10, "abc", past)
+	tus.lock(busy)
+
+	removed, err := tus.Cleanup(stdctx.Background())
+	if err != nil || removed != 1 {
+		t.Fatalf("Cleanup = %d, %v; want 1 removed", removed, err)
+	}
+	want := []string{"tus/" + active, "tus/" + active + ".info", "tus/" + finished, "tus/" + finished + ".info",
+		"tus/" + busy, "tus/" + busy + ".info"}
+	sort.Strings(want)
+	if got := storage.Names(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+}
+
+func TestTusProtocol(t *testing.T) {
+	var completed []string
+	s := New()
+	s.SetMode(Release)
+	tus := NewTusHandler(TusOptions{
+		Storage: NewMemoryStorage(),
+		MaxSize: 100,
+		OnComplete: func(c Context, upload *TusUpload) {
+			completed = append(completed, upload.ID)
+		},
+	})
+	tus.Mount(s.Root("files"))
+
+	do := func(method, target string, headers map[string]string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Tus-Resumable", TusVersion)
+		for k, v := range headers {
+			if v == "" {
+				req.Header.Del(k)
+			} else {
+				req.Header.Set(k, v)
+			}
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+	checksum := func(data string) string {
+		sum := sha1.Sum([]byte(data))
+		return "sha1 " + base64.StdEncoding.EncodeToString(sum[:])
+	}
+
+	w := do(http.MethodPost, "/files", map[string]string{"Upload-Length": "11", "Upload-Metadata": "filename aGVsbG8udHh0"}, "")
+	if w.Code != http.StatusCreated || !strings.HasPrefix(w.Header().Get("Location"), "/files/") {
+		t.Fatalf("create = %d, Location %q", w.Code, w.Header().Get("Location"))
+	}
+	upload := w.Header().Get("Location")
+	id := path.Base(upload)
+	const chunk = "application/offset+octet-stream"
+
+	steps := []struct {
+		name       string
+		method     string
+		target     string
+		headers    map[string]string
+		body       string
+		want       int
+		wantOffset string
+	}{
+		{"options", http.MethodOptions, "/files", nil, "", http.StatusNoContent, ""},
+		{"create without version", http.MethodPost, "/files", map[string]string{"Tus-Resumable": "", "Upload-Length": "1"}, "", http.StatusPreconditionFailed, ""},
+		{"create with other version", http.MethodPost, "/files", map[string]string{"Tus-Resumable": "0.2.2", "Upload-Length": "1"}, "", http.StatusPreconditionFailed, ""},
+		{"create without length", http.MethodPost, "/files", nil, "", http.StatusBadRequest, ""},
+		{"create with negative length", http.MethodPost, "/files", map[string]string{"Upload-Length": "-1"}, "", http.StatusBadRequest, ""},
+		{"create too large", http.MethodPost, "/files", map[string]string{"Upload-Length": "101"}, "", http.StatusRequestEntityTooLarge, ""},
+		{"create with bad metadata", http.MethodPost, "/files", map[string]string{"Upload-Length": "1", "Upload-Metadata": "name !!!"}, "", http.StatusBadRequest, ""},
+		{"head", http.MethodHead, upload, nil, "", http.StatusOK, "0"},
+		{"head without version", http.MethodHead, upload, map[string]string{"Tus-Resumable": ""}, "", http.StatusPreconditionFailed, ""},
+		{"head unknown", http.MethodHead, "/files/00000000000000000000000000000000", nil, "", http.StatusNotFound, ""},
+		{"head invalid id", http.MethodHead, "/files/not-an-upload-id", nil, "", http.StatusNotFound, ""},
+		{"patch without version", http.MethodPatch, upload, map[string]string{"Tus-Resumable": "", "Content-Type": chunk, "Upload-Offset": "0"}, "hello ", http.StatusPreconditionFailed, ""},
+		{"patch wrong content type", http.MethodPatch, upload, map[string]string{"Content-Type": "application/octet-stream", "Upload-Offset": "0"}, "hello ", http.StatusUnsupportedMediaType, ""},
+		{"patch wrong offset", http.MethodPatch, upload, map[string]string{"Content-Type": chunk, "Upload-Offset": "5"}, "hello ", http.StatusConflict, ""},
+		{"patch without offset", http.MethodPatch, upload, map[string]string{"Content-Type": chunk}, "hello ", http.StatusConflict, ""},
+		{"patch checksum mismatch", http.MethodPatch, upload, map[string]string{"Content-Type": chunk, "Upload-Offset": "0", "Upload-Checksum": checksum("other")}, "hello ", StatusChecksumMismatch, ""},
+		{"head after mismatch", http.MethodHead, upload, nil, "", http.StatusOK, "0"},
+		{"patch unknown checksum", http.MethodPatch, upload, map[string]string{"Content-Type": chunk, "Upload-Offset": "0", "Upload-Checksum": "crc32 AAAA"}, "hello ", http.StatusBadRequest, ""},
+		{"patch", http.MethodPatch, upload, map[string]string{"Content-Type": chunk, "Upload-Offset": "0", "Upload-Checksum": checksum("hello ")}, "hello ", http.StatusNoContent, "6"},
+		{"patch beyond length", http.MethodPatch, upload, map[string]string{"Content-Type": chunk, "Upload-Offset": "6"}, "world and more", http.StatusRequestEntityTooLarge, ""},
+		{"head after too large", http.MethodHead, upload, nil, "", http.StatusOK, "6"},
+		{"patch last chunk", http.MethodPatch, upload, map[string]string{"Content-Type": chunk, "Upload-Offset": "6"}, "world", http.StatusNoContent, "11"},
+		{"delete", http.MethodDelete, upload, nil, "", http.StatusNoContent, ""},
+		{"head after delete", http.MethodHead, upload, nil, "", http.StatusNotFound, ""},
+	}
+	for _, st := range steps {
+		w := do(st.method, st.target, st.headers, st.body)
+		if w.Code != st.want {
+			t.Errorf("%s: status %d %q, want %d", st.name, w.Code, w.Body.String(), st.want)
+			continue
+		}
+		if got := w.Header().Get("Tus-Resumable"); got != TusVersion {
+			t.Errorf("%s: Tus-Resumable %q", st.name, got)
+		}
+		if st.want == http.StatusPreconditionFailed && w.Header().Get("Tus-Version") != TusVersion {
+			t.Errorf("%s: missing Tus-Version", st.name)
+		}
+		if st.wantOffset != "" && w.Header().Get("Upload-Offset") != st.wantOffset {
+			t.Errorf("%s: Upload-Offset %q, want %q", st.name, w.Header().Get("Upload-Offset"), st.wantOffset)
+		}
+		if st.name == "head" && (w.Header().Get("Upload-Length") != "11" || w.Header().Get("Upload-Metadata") != "filename aGVsbG8udHh0") {
+			t.Errorf("head: Upload-Length %q, Upload-Metadata %q", w.Header().Get("Upload-Length"), w.Header().Get("Upload-Metadata"))
+		}
+	}
+	if len(completed) != 1 || completed[0] != id {
+		t.Errorf("completed %v, want [%s]", completed, id)
+	}
+}
+
+func TestTusCreate(t *testing.T) {
+	tests := []struct {
+		name       string
+		length     string
+		body       string
+		wantOffset string
+		complete   bool
+	}{
+		{"empty upload", "0", "", "", true},
+		{"with upload", "5", "hello", "5", true},
+		{"with partial upload", "10", "hello", "5", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var completed int
+			s := New()
+			s.SetMode(Release)
+			NewTusHandler(TusOptions{
+				Storage:    NewMemoryStorage(),
+				OnComplete: func(c Context, upload *TusUpload) { completed++ },
+			}).Mount(s.Root("files"))
+
+			req := httptest.NewRequest(http.MethodPost, "/files", strings.NewReader(tt.body))
+			req.Header.Set("Tus-Resumable", TusVersion)
+			req.Header.Set("Upload-Length", tt.length)
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/offset+octet-stream")
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, req)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("status %d %q", w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Upload-Offset"); got != tt.wantOffset {
+				t.Errorf("Upload-Offset %q, want %q", got, tt.wantOffset)
+			}
+			if (completed == 1) != tt.complete || completed > 1 {
+				t.Errorf("OnComplete called %d times, want complete %v", completed, tt.complete)
+			}
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package slimgo
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	// ErrUploadTooLarge is returned when a file or all files together exceed their limit.
+	ErrUploadTooLarge = errors.New("upload too large")
+	// ErrUploadType is returned when the sniffed content type of a file is not allowed.
+	ErrUploadType = errors.New("upload type not allowed")
+	// ErrNoUpload is returned when the request has no file.
+	ErrNoUpload = errors.New("no file uploaded")
+)
+
+// maxUploadFormValues limits the size of the non-file fields of an upload.
+const maxUploadFormValues = 1 << 20
+
+// sniffLen is the number of bytes http.DetectContentType looks at.
+const sniffLen = 512
+
+// UploadOptions configures Context.Upload.
+type UploadOptions struct {
+	// MaxFileSize is the limit of each file in bytes, 0 means no limit.
+	MaxFileSize int64
+	// MaxTotalSize is the limit of all files together in bytes, 0 means no limit.
+	MaxTotalSize int64
+	// MaxFiles is the maximum number of files, 0 means no limit.
+	MaxFiles int
+	// AllowedTypes lists the allowed content types, sniffed from the data and
+	// not taken from the client. "image/*" allows all images. Empty allows all.
+	AllowedTypes []string
+	// KeepFilename stores files under the sanitized client filename instead
+	// of a random one. Existing files are never overwritten.
+	// Either way the extension must belong to the sniffed content type,
+	// otherwise it is replaced, so "evil.html" holding a GIF becomes "evil.gif".
+	KeepFilename bool
+	// AfterStore is called for every stored file, e.g. to scan it for viruses
+	// or create a thumbnail. An error rejects the upload and deletes the files.
+	AfterStore func(file *UploadedFile, storage UploadStorage) error
+
+	// accept is used by SaveUploadFiles to skip files by name.
+	accept func(filename string) bool
+	// acceptExt is used by SaveUploadFiles to reject files whose stored
+	// extension, which follows the sniffed type, is not allowed.
+	acceptExt func(ext string) bool
+}
+
+// UploadedFile describes a stored file.
+type UploadedFile struct {
+	// FieldName is the form field of the file.
+	FieldName string
+	// Filename is the filename sent by the client, for display only.
+	Filename string
+	// Name is the path of the file relative to the upload folder, with "/" separators.
+	Name string
+	// Size is the number of bytes stored.
+	Size int64
+	// SHA256 is the hex encoded SHA-256 of the data.
+	SHA256 string
+	// ContentType is sniffed from the data with http.DetectContentType.
+	ContentType string
+}
+
+// Upload streams the files of a multipart request into folder, in a sub
+// folder named after the current date. The other form fields are available
+// from Request().PostForm afterwards.
+// When any file fails all files stored by this call are removed again.
+func (c *context) Upload(folder string, opts UploadOptions) ([]*UploadedFile, error) {
+	return c.UploadTo(NewLocalStorage(folder), opts)
+}
+
+// UploadTo is Upload with the files streamed into storage.
+func (c *context) UploadTo(storage UploadStorage, opts UploadOptions) ([]*UploadedFile, error) {
+	reader, err := c.request.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	if c.request.PostForm == nil {
+		c.request.PostForm = make(url.Values)
+	}
+
+	var (
+		files      []*UploadedFile
+		total      int64
+		formValues int64
+	)
+	fail := func(err error) ([]*UploadedFile, error) {
+		for _, f := range files {
+			_ = storage.Delete(f.Name)
+		}
+		return nil, err
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+
+		if part.FileName() == "" {
+			// a plain form field
+			b, err := ioutil.ReadAll(io.LimitReader(part, maxUploadFormValues-formValues+1))
+			_ = part.Close()
+			if err != nil {
+				return fail(err)
+			}
+			if formValues += int64(len(b)); formValues > maxUploadFormValues {
+				return fail(fmt.Errorf("%w: form values exceed %d bytes", ErrUploadTooLarge, maxUploadFormValues))
+			}
+			c.request.PostForm.Add(part.FormName(), string(b))
+			continue
+		}
+		if opts.accept != nil && !opts.accept(part.FileName()) {
+			_ = part.Close()
+			continue
+		}
+		if opts.MaxFiles > 0 && len(files) >= opts.MaxFiles {
+			_ = part.Close()
+			return fail(fmt.Errorf("%w: more than %d files", ErrUploadTooLarge, opts.MaxFiles))
+		}
+
+		limit := int64(-1)
+		if opts.MaxFileSize > 0 {
+			limit = opts.MaxFileSize
+		}
+		if opts.MaxTotalSize > 0 && (limit < 0 || opts.MaxTotalSize-total < limit) {
+			limit = opts.MaxTotalSize - total
+		}
+
+		f, err := saveUploadPart(storage, part, limit, &opts)
+		_ = part.Close()
+		if err != nil {
+			return fail(err)
+		}
+		total += f.Size
+		files = append(files, f)
+
+		if opts.AfterStore != nil {
+			if err := opts.AfterStore(f, storage); err != nil {
+				return fail(err)
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, ErrNoUpload
+	}
+	return files, nil
+}
+
+// saveUploadPart stores part in storage. At most limit bytes are accepted,
+// a negative limit means no limit.
+func saveUploadPart(storage UploadStorage, part *multipart.Part, limit int64, opts *UploadOptions) (*UploadedFile, error) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(part, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	if !typeAllowed(contentType, opts.AllowedTypes) {
+		return nil, fmt.Errorf("%w: %s is %s", ErrUploadType, part.FileName(), contentType)
+	}
+
+	filename := sanitizeFilename(part.FileName())
+	ext := uploadExt(filename, contentType)
+	if opts.acceptExt != nil && !opts.acceptExt(ext) {
+		return nil, fmt.Errorf("%w: %s is %s", ErrUploadType, part.FileName(), contentType)
+	}
+
+	h := sha256.New()
+	src := io.TeeReader(&limitedReader{r: io.MultiReader(bytes.NewReader(head), part), limit: limit}, h)
+	name, size, err := storeUpload(storage, time.Now().Format("2006-01-02"), filename, ext, opts.KeepFilename, src)
+	if err != nil {
+		if errors.Is(err, ErrUploadTooLarge) {
+			err = fmt.Errorf("%w: %s exceeds %d bytes", ErrUploadTooLarge, part.FileName(), limit)
+		}
+		return nil, err
+	}
+
+	return &UploadedFile{
+		FieldName:   part.FormName(),
+		Filename:    part.FileName(),
+		Name:        name,
+		Size:        size,
+		SHA256:      hex.EncodeToString(h.Sum(nil)),
+		ContentType: contentType,
+	}, nil
+}
+
+// limitedReader fails with ErrUploadTooLarge after limit bytes, counting what
+// is really read instead of trusting Content-Length. A negative limit means
+// no limit.
+type limitedReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.limit >= 0 && l.read > l.limit {
+		return n, ErrUploadTooLarge
+	}
+	return n, err
+}
+
+// storeUpload saves r under a new name with ext in dir and returns the name.
+// Storages never overwrite, so a taken name is retried with another one.
+func storeUpload(storage UploadStorage, dir, filename, ext string, keepFilename bool, r io.Reader) (string, int64, error) {
+	base := filename[:len(filename)-len(path.Ext(filename))]
+
+	for i := 0; i < 100; i++ {
+		var name string
+		switch {
+		case !keepFilename || base == "":
+			name = randomFilename() + ext
+		case i == 0:
+			name = base + ext
+		default:
+			name = base + "-" + strconv.Itoa(i) + ext
+		}
+
+		name = path.Join(dir, name)
+		n, err := storage.Save(name, r)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		return name, n, err
+	}
+	return "", 0, fmt.Errorf("can not create a unique file for %q", filename)
+}
+
+// sanitizeFilename keeps only the last element of a client filename and
+// replaces anything but letters, digits, '.', '-' and '_'. It returns "" if
+// nothing usable is left.
+func sanitizeFilename(filename string) string {
+	filename = strings.Replace(filename, "\\", "/", -1)
+	filename = path.Base(filename)
+
+	var sb strings.Builder
+	for _, r := range filename {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '-' || r == '_':
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+	// no hidden files, no "." or ".."
+	name := strings.TrimLeft(sb.String(), ".")
+	if len(name) > 200 {
+		ext := safeExt(name)
+		name = strings.ToValidUTF8(name[:200-len(ext)], "") + ext
+	}
+	return name
+}
+
+// safeExt returns the lower-case extension of filename if it is short and
+// alphanumeric, otherwise "".
+func safeExt(filename string) string {
+	ext := strings.ToLower(path.Ext(filename))
+	if len(ext) < 2 || len(ext) > 16 {
+		return ""
+	}
+	for _, r := range ext[1:] {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9') {
+			return ""
+		}
+	}
+	return ext
+}
+
+// preferredExts picks the extension of sniffed types with several ones.
+var preferredExts = map[string]string{
+	"text/plain":               ".txt",
+	"text/html":                ".html",
+	"text/xml":                 ".xml",
+	"image/jpeg":               ".jpg",
+	"image/x-icon":             ".ico",
+	"application/octet-stream": ".bin",
+	"application/x-gzip":       ".gz",
+	"audio/mpeg":               ".mp3",
+	"video/mp4":                ".mp4",
+}
+
+// uploadExt returns the extension to store a file with: the extension of
+// filename if it belongs to the sniffed contentType, else one of the type.
+// The client extension is never trusted on its own, or a server which serves
+// the uploads would render a GIF named "x.html" as a page.
+func uploadExt(filename, contentType string) string {
+	mediaType := baseMediaType(contentType)
+	exts, _ := mime.ExtensionsByType(mediaType)
+	if ext := safeExt(filename); ext != "" {
+		for _, e := range exts {
+			if e == ext {
+				return ext
+			}
+		}
+	}
+	if ext, ok := preferredExts[mediaType]; ok {
+		return ext
+	}
+	if len(exts) == 0 {
+		return ""
+	}
+	sorted := append([]string(nil), exts...)
+	sort.Strings(sorted)
+	return safeExt(sorted[0])
+}
+
+func randomFilename() string {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// typeAllowed reports whether contentType matches one of allowed, which may
+// end with "/*" to match a whole group like "image/*".
+func typeAllowed(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	mediaType := baseMediaType(contentType)
+
+	for _, a := range allowed {
+		if strings.HasSuffix(a, "/*") {
+			if strings.HasPrefix(mediaType, strings.TrimSuffix(a, "*")) {
+				return true
+			}
+		} else if strings.EqualFold(a, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// baseMediaType strips the parameters from contentType, e.g. the charset
+// from "text/plain; charset=utf-8".
+func baseMediaType(contentType string) string {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.TrimSpace(contentType)
+}
@@ -0,0 +1,299 @@
+package slimgo
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// UploadStorage stores uploaded files. Names are relative paths with "/"
+// separators, e.g. "2006-01-02/name.png".
+//
+// An S3-compatible backend maps Save, Open and Delete to PutObject (with
+// If-None-Match: *), GetObject and DeleteObject.
+type UploadStorage interface {
+	// Save writes r to a new file name and returns the number of bytes
+	// written. If name exists it returns an error matching os.ErrExist
+	// without reading r. When r fails nothing must be left behind.
+	Save(name string, r io.Reader) (int64, error)
+	// Open opens the file name, the error matches os.ErrNotExist if it is missing.
+	Open(name string) (io.ReadCloser, error)
+	// Delete removes the file name. Deleting a missing file is not an error.
+	Delete(name string) error
+}
+
+// UploadAppender is an UploadStorage which can also grow files, as needed
+// by resumable uploads.
+type UploadAppender interface {
+	UploadStorage
+	// Append writes r to the end of the existing file name and returns the
+	// number of bytes written. When r fails the bytes read so far are kept.
+	Append(name string, r io.Reader) (int64, error)
+	// Truncate shrinks the file name to size bytes.
+	Truncate(name string, size int64) error
+	// Size returns the size of the file name.
+	Size(name string) (int64, error)
+}
+
+// UploadLister is an UploadStorage which can list its files, as needed by
+// TusHandler.Cleanup.
+type UploadLister interface {
+	UploadStorage
+	// List returns the names of the files directly in dir, sorted.
+	List(dir string) ([]string, error)
+}
+
+// LocalStorage stores files in a folder of the local file system.
+type LocalStorage struct {
+	root string
+}
+
+var (
+	_ UploadAppender = (*LocalStorage)(nil)
+	_ UploadLister   = (*LocalStorage)(nil)
+)
+
+// NewLocalStorage creates an UploadStorage which keeps files under root.
+func NewLocalStorage(root string) *LocalStorage {
+	return &LocalStorage{root: root}
+}
+
+// file returns the path of name, which can not leave the root folder.
+func (s *LocalStorage) file(name string) (string, error) {
+	clean := strings.TrimPrefix(path.Clean("/"+name), "/")
+	if clean == "" {
+		return "", &os.PathError{Op: "open", Path: name, Err: os.ErrInvalid}
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
+
+func (s *LocalStorage) Save(name string, r io.Reader) (int64, error) {
+	file, err := s.file(name)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return 0, err
+	}
+
+	// O_EXCL makes sure no existing file is overwritten
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file)
+		return n, err
+	}
+	return n, nil
+}
+
+func (s *LocalStorage) Open(name string) (io.ReadCloser, error) {
+	file, err := s.file(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(file)
+}
+
+func (s *LocalStorage) Delete(name string) error {
+	file, err := s.file(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) Append(name string, r io.Reader) (int64, error) {
+	file, err := s.file(name)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
+
+func (s *LocalStorage) Truncate(name string, size int64) error {
+	file, err := s.file(name)
+	if err != nil {
+		return err
+	}
+	return os.Truncate(file, size)
+}
+
+func (s *LocalStorage) Size(name string) (int64, error) {
+	file, err := s.file(name)
+	if err != nil {
+		return 0, err
+	}
+	fi, err := os.Stat(file)
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
+
+func (s *LocalStorage) List(dir string) ([]string, error) {
+	folder, err := s.file(dir)
+	if err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(folder)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for _, fi := range files {
+		if !fi.IsDir() {
+			names = append(names, path.Join(dir, fi.Name()))
+		}
+	}
+	return names, nil
+}
+
+// MemoryStorage keeps files in memory, for tests and as a stand-in for
+// remote backends.
+type MemoryStorage struct {
+	mu    sync.RWMutex
+	files map[string][]byte
+}
+
+var (
+	_ UploadAppender = (*MemoryStorage)(nil)
+	_ UploadLister   = (*MemoryStorage)(nil)
+)
+
+// NewMemoryStorage creates an in-memory UploadStorage.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{files: make(map[string][]byte)}
+}
+
+func (m *MemoryStorage) Save(name string, r io.Reader) (int64, error) {
+	// reserve the name before reading, so concurrent saves can not both win
+	m.mu.Lock()
+	if _, ok := m.files[name]; ok {
+		m.mu.Unlock()
+		return 0, &os.PathError{Op: "save", Path: name, Err: os.ErrExist}
+	}
+	m.files[name] = nil
+	m.mu.Unlock()
+
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, r)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		delete(m.files, name)
+		return n, err
+	}
+	m.files[name] = buf.Bytes()
+	return n, nil
+}
+
+func (m *MemoryStorage) Open(name string) (io.ReadCloser, error) {
+	m.mu.RLock()
+	b, ok := m.files[name]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (m *MemoryStorage) Delete(name string) error {
+	m.mu.Lock()
+	delete(m.files, name)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *MemoryStorage) Append(name string, r io.Reader) (int64, error) {
+	m.mu.RLock()
+	_, ok := m.files[name]
+	m.mu.RUnlock()
+	if !ok {
+		return 0, &os.PathError{Op: "append", Path: name, Err: os.ErrNotExist}
+	}
+
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, r)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.files[name]
+	if !ok {
+		return 0, &os.PathError{Op: "append", Path: name, Err: os.ErrNotExist}
+	}
+	m.files[name] = append(b, buf.Bytes()...)
+	return n, err
+}
+
+func (m *MemoryStorage) Truncate(name string, size int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.files[name]
+	if !ok {
+		return &os.PathError{Op: "truncate", Path: name, Err: os.ErrNotExist}
+	}
+	if size < int64(len(b)) {
+		m.files[name] = b[:size:size]
+	}
+	return nil
+}
+
+func (m *MemoryStorage) Size(name string) (int64, error) {
+	m.mu.RLock()
+	b, ok := m.files[name]
+	m.mu.RUnlock()
+	if !ok {
+		return 0, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	return int64(len(b)), nil
+}
+
+// Names returns the names of all files, sorted.
+func (m *MemoryStorage) Names() []string {
+	m.mu.RLock()
+	names := make([]string, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+	m.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+func (m *MemoryStorage) List(dir string) ([]string, error) {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	var names []string
+	for _, name := range m.Names() {
+		if strings.HasPrefix(name, prefix) && !strings.Contains(name[len(prefix):], "/") {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
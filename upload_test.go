@@ -0,0 +1,170 @@
+package slimgo
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"report.pdf", "report.pdf"},
+		{"../../etc/passwd", "passwd"},
+		{`..\..\windows\win.ini`, "win.ini"},
+		{"/abs/path/a.txt", "a.txt"},
+		{"..", ""},
+		{".", ""},
+		{".htaccess", "htaccess"},
+		{"a/..", ""},
+		{"name\x00.jpg", "name_.jpg"},
+		{"my file;rm -rf.txt", "my_file_rm_-rf.txt"},
+		{"报告.pdf", "报告.pdf"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeFilename(tt.in); got != tt.want {
+			t.Errorf("sanitizeFilename(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestUploadExtensionFollowsContent(t *testing.T) {
+	gif := []byte("GIF89a<script>alert(1)</script>")
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...)
+
+	tests := []struct {
+		name     string
+		filename string
+		data     []byte
+		keep     bool
+		wantExt  string
+		wantBase string
+	}{
+		{"html holding gif", "evil.html", gif, false, ".gif", ""},
+		{"html holding gif kept", "evil.html", gif, true, ".gif", "evil"},
+		{"matching extension kept", "logo.png", png, true, ".png", "logo"},
+		{"upper case extension", "LOGO.PNG", png, true, ".png", "LOGO"},
+		{"no extension", "logo", png, true, ".png", "logo"},
+		{"traversal", "../../logo.png", png, true, ".png", "logo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := NewMemoryStorage()
+			s := New()
+			s.SetMode(Release)
+			var files []*UploadedFile
+			var uploadErr error
+			s.POST("/upload", func(c Context) {
+				files, uploadErr = c.UploadTo(storage, UploadOptions{
+					AllowedTypes: []string{"image/*"},
+					KeepFilename: tt.keep,
+				})
+			})
+
+			var body bytes.Buffer
+			mw := multipart.NewWriter(&body)
+			fw, _ := mw.CreateFormFile("file", tt.filename)
+			_, _ = fw.Write(tt.data)
+			_ = mw.Close()
+			req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+			req.Header.Set("Content-Type", mw.FormDataContentType())
+			s.ServeHTTP(httptest.NewRecorder(), req)
+
+			if uploadErr != nil || len(files) != 1 {
+				t.Fatalf("upload: %v, %d files", uploadErr, len(files))
+			}
+			name := path.Base(files[0].Name)
+			if ext := path.Ext(name); ext != tt.wantExt {
+				t.Errorf("stored as %q, want extension %q", name, tt.wantExt)
+			}
+			if tt.wantBase != "" && name != tt.wantBase+tt.wantExt {
+				t.Errorf("stored as %q, want %q", name, tt.wantBase+tt.wantExt)
+			}
+		})
+	}
+}
+
+func TestSaveUploadFilesChecksStoredExtension(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...)
+	jpg := append([]byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), make([]byte, 32)...)
+	gif := []byte("GIF89a" + strings.Repeat("\x00", 32))
+	html := []byte("<!DOCTYPE html><html><script>alert(document.cookie)</script></html>")
+	svg := []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"/>`)
+
+	tests := []struct {
+		name     string
+		filename string
+		data     []byte
+		wantExt  string
+		wantErr  error
+	}{
+		{"png", "avatar.png", png, ".png", nil},
+		{"jpg", "photo.jpg", jpg, ".jpg", nil},
+		{"html named png", "avatar.png", html, "", ErrUploadType},
+		{"svg named jpg", "photo.jpg", svg, "", ErrUploadType},
+		{"gif named png", "anim.png", gif, "", ErrUploadType},
+		{"png named jpg", "photo.jpg", png, ".png", nil},
+		{"html named html", "page.html", html, "", errNoFile},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "slimgo_upload")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			s := New()
+			s.SetMode(Release)
+			var names []string
+			var uploadErr error
+			s.POST("/upload", func(c Context) {
+				names, uploadErr = c.SaveUploadFiles(dir, 1<<20, "(.png|.jpg)")
+			})
+
+			var body bytes.Buffer
+			mw := multipart.NewWriter(&body)
+			fw, _ := mw.CreateFormFile("file", tt.filename)
+			_, _ = fw.Write(tt.data)
+			_ = mw.Close()
+			req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+			req.Header.Set("Content-Type", mw.FormDataContentType())
+			s.ServeHTTP(httptest.NewRecorder(), req)
+
+			switch {
+			case tt.wantErr == errNoFile:
+				if uploadErr == nil {
+					t.Errorf("stored %v, want no file", names)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(uploadErr, tt.wantErr) {
+					t.Errorf("error = %v, want %v", uploadErr, tt.wantErr)
+				}
+			case uploadErr != nil || len(names) != 1:
+				t.Fatalf("upload: %v, %v", uploadErr, names)
+			case path.Ext(names[0]) != tt.wantExt:
+				t.Errorf("stored as %q, want extension %q", names[0], tt.wantExt)
+			}
+
+			// nothing but the allowed files may end up in the folder
+			_ = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+				if err == nil && !info.IsDir() && !strings.HasSuffix(p, ".png") && !strings.HasSuffix(p, ".jpg") {
+					t.Errorf("stored %s", p)
+				}
+				return nil
+			})
+		})
+	}
+}
+
+// errNoFile marks SaveUploadFiles cases where the file is skipped by name.
+var errNoFile = errors.New("no file")
@@ -4,10 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"net/url"
 	"os"
 	"path"
 	"reflect"
 	"runtime"
+	"strings"
 	"unsafe"
 )
 
@@ -196,3 +198,27 @@ func filterFlags(content string) string {
 	}
 	return content
 }
+
+// isSafeRedirect reports whether target stays on host: a relative path, or
+// an http(s) URL with the same host. Browsers read "//x" and "/\x" as
+// another host, so those are rejected too.
+func isSafeRedirect(target, host string) bool {
+	if target == "" || strings.ContainsAny(target, "\\\x00\t\r\n") || strings.TrimSpace(target) != target {
+		return false
+	}
+	if strings.HasPrefix(target, "//") {
+		return false
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" && u.Host == "" {
+		return true
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	return u.Host != "" && strings.EqualFold(u.Host, host)
+}
@@ -0,0 +1,43 @@
+package slimgo
+
+import "testing"
+
+func TestIsSafeRedirect(t *testing.T) {
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{"/home", true},
+		{"/home?next=//evil.com", true},
+		{"home", true},
+		{"../up", true},
+		{"?page=2", true},
+		{"http://example.com/home", true},
+		{"https://EXAMPLE.com/home", true},
+		{"", false},
+		{"//evil", false},
+		{"//evil.com/path", false},
+		{"///evil.com", false},
+		{`/\evil`, false},
+		{`\\evil.com`, false},
+		{"https:evil", false},
+		{"https:evil.com/path", false},
+		{"http:/evil.com", false},
+		{"https://evil.com", false},
+		{"https://example.com.evil.com/", false},
+		{"https://example.com@evil.com/", false},
+		{"javascript:alert(1)", false},
+		{"JavaScript:alert(1)", false},
+		{"data:text/html,<script>alert(1)</script>", false},
+		{"ftp://example.com/", false},
+		{" //evil.com", false},
+		{"/\t/evil.com", false},
+		{"/\r\nLocation: https://evil.com", false},
+		{"/home\x00", false},
+	}
+	for _, tt := range tests {
+		if got := isSafeRedirect(tt.target, "example.com"); got != tt.want {
+			t.Errorf("isSafeRedirect(%q) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}